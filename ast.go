@@ -1,7 +1,12 @@
 package regexl
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
 )
 
 const (
@@ -11,6 +16,27 @@ const (
 type Ast struct {
 	Tokens []Token
 	Nodes  []Node
+	// Comments are the comments of the query by the node they are attached to.
+	//
+	// Comments are only attached to nodes that can be put on their own line, which are top level nodes, select expressions,
+	// parts of '+' chains, function arguments and object key-value pairs
+	Comments map[Node][]Comment
+
+	// errs are the errors found by Gen so far. Gen continues after most errors so that all of them are reported at once
+	errs ErrorList
+	// incomplete are the function calls that had errors inside their brackets, so some of their arguments might be missing
+	incomplete map[Node]bool
+}
+
+// Comment is a '//' comment of the query
+type Comment struct {
+	Pos TokenPos
+	// Text is the comment without the starting '//'
+	Text string
+	// IsTrailing is true if the comment comes after the node it is attached to, otherwise the comment is on a line before the node
+	IsTrailing bool
+	// IsOwnLine is true if the comment is the first thing on its line
+	IsOwnLine bool
 }
 
 var _ error = &AstError{}
@@ -18,6 +44,23 @@ var _ error = &AstError{}
 type AstError struct {
 	Err error
 	Pos TokenPos
+	// End is the position after the last byte of what caused the error. If End isn't after Pos the error is about the character at Pos
+	End TokenPos
+
+	// query is set by setErrorsQuery
+	query string
+}
+
+func (te *AstError) Span() (start, end TokenPos) {
+	return errorSpan(te.Pos, te.End)
+}
+
+func (te *AstError) Unwrap() error {
+	return te.Err
+}
+
+func (te *AstError) setQuery(query string) {
+	te.query = query
 }
 
 func (te *AstError) Error() string {
@@ -26,7 +69,7 @@ func (te *AstError) Error() string {
 		return ""
 	}
 
-	return fmt.Sprintf("ast error: loc=%d; err=%s", te.Pos, te.Err.Error())
+	return fmt.Sprintf("ast error: loc=%s; err=%s", errorLoc(te.query, te.Pos), te.Err.Error())
 }
 
 //
@@ -105,7 +148,7 @@ type BinaryExpr struct {
 }
 
 func (e *BinaryExpr) expr()              {}
-func (e *BinaryExpr) StartPos() TokenPos { return e.Pos }
+func (e *BinaryExpr) StartPos() TokenPos { return e.Lhs.StartPos() }
 func (e *BinaryExpr) EndPos() TokenPos   { return e.Rhs.EndPos() }
 
 type LiteralExpr struct {
@@ -113,11 +156,25 @@ type LiteralExpr struct {
 	Type TokenType
 	// Value depends on the type, so it can contain a numeric, string etc
 	Value string
+	// Raw is the string as written in the query including its quotes and escapes, and is only set for strings parsed from a query
+	Raw string
 }
 
 func (e *LiteralExpr) expr()              {}
 func (e *LiteralExpr) StartPos() TokenPos { return e.Pos }
-func (e *LiteralExpr) EndPos() TokenPos   { return e.Pos + TokenPos(len(e.Value)) }
+func (e *LiteralExpr) EndPos() TokenPos {
+
+	if e.Type == TokenType_String && e.Raw != "" {
+		return e.Pos + TokenPos(len(e.Raw))
+	}
+
+	// Pos of strings is the position of the opening quote, and Value doesn't have the quotes
+	if e.Type == TokenType_String {
+		return e.Pos + TokenPos(len(e.Value)) + 2
+	}
+
+	return e.Pos + TokenPos(len(e.Value))
+}
 
 type KeyValExpr struct {
 	Key      IdentExpr
@@ -142,28 +199,192 @@ func (e *ObjectLiteralExpr) EndPos() TokenPos   { return e.CloseCurly + 1 }
 func NewAst(tokens []Token) *Ast {
 
 	ast := &Ast{
-		Tokens: tokens,
-		Nodes:  make([]Node, 0, 5),
+		Tokens:   tokens,
+		Nodes:    make([]Node, 0, 5),
+		Comments: map[Node][]Comment{},
 	}
 
 	return ast
 }
 
+// Gen generates the nodes of the AST from its tokens. Gen doesn't stop at the first error, so the returned error is an ErrorList with all the errors found,
+// and the AST has all the nodes that could be generated
 func (a *Ast) Gen() error {
 
+	a.errs = ErrorList{}
+	a.incomplete = map[Node]bool{}
+
 	i := 0
 	for i < len(a.Tokens) {
 
 		n, lastProcessedIndex, err := a.parseFrom(i)
 		if err != nil {
-			return err
+			a.errs.Add(err)
+			i = a.indexAfterError(err, i)
+			continue
+		}
+
+		// No node means only comments were left
+		if n != nil {
+			a.Nodes = append(a.Nodes, n)
 		}
 
-		a.Nodes = append(a.Nodes, n)
 		i = lastProcessedIndex + 1
 	}
 
-	return nil
+	a.attachComments()
+	return a.errs.Err()
+}
+
+// IsIncomplete returns true if Gen found errors inside the brackets of the function call, in which case some of its arguments might be missing.
+// Backends don't check the arguments of incomplete calls, as the problems found would be caused by the errors Gen already reported
+func (a *Ast) IsIncomplete(n Node) bool {
+	return a.incomplete[n]
+}
+
+// attachComments fills Ast.Comments. A comment at the end of a line is attached to the node that ends last on that line,
+// otherwise the comment is attached to the first node after it. When multiple nodes end or start at the same place the outermost node is used.
+// Comments after all nodes are attached to the last top level node
+func (a *Ast) attachComments() {
+
+	a.Comments = map[Node][]Comment{}
+	if len(a.Nodes) == 0 {
+		return
+	}
+
+	// Targets are in pre-order, so stable sorting keeps outer nodes before the inner nodes that end or start at the same place
+	targets := a.commentTargets()
+	byEnd := slices.Clone(targets)
+	sort.SliceStable(byEnd, func(i, j int) bool { return byEnd[i].end < byEnd[j].end })
+	byStart := slices.Clone(targets)
+	sort.SliceStable(byStart, func(i, j int) bool { return byStart[i].start < byStart[j].start })
+
+	for i := 0; i < len(a.Tokens); i++ {
+
+		t := &a.Tokens[i]
+		if t.Type != TokenType_Comment {
+			continue
+		}
+
+		c := Comment{
+			Pos:       t.Pos,
+			Text:      t.Val,
+			IsOwnLine: i == 0 || a.Tokens[i-1].Line != t.Line,
+		}
+
+		var target Node
+		if !c.IsOwnLine {
+
+			// The node ending last before the comment is the only one that can be on its line, as nodes ending earlier are on the same line or before it
+			lastEndIndex := sort.Search(len(byEnd), func(i int) bool { return byEnd[i].end > t.Pos }) - 1
+			if lastEndIndex >= 0 && a.lineOf(byEnd[lastEndIndex].end-1) == t.Line {
+
+				lastEnd := byEnd[lastEndIndex].end
+				target = byEnd[sort.Search(len(byEnd), func(i int) bool { return byEnd[i].end >= lastEnd })].node
+			}
+
+			c.IsTrailing = target != nil
+		}
+
+		if target == nil {
+
+			firstStartIndex := sort.Search(len(byStart), func(i int) bool { return byStart[i].start > t.Pos })
+			if firstStartIndex < len(byStart) {
+				target = byStart[firstStartIndex].node
+			}
+		}
+
+		if target == nil {
+			target = a.Nodes[len(a.Nodes)-1]
+			c.IsTrailing = true
+		}
+
+		a.Comments[target] = append(a.Comments[target], c)
+	}
+}
+
+// commentTarget is a node comments can be attached to, with its start and end found once as finding them walks the node
+type commentTarget struct {
+	node  Node
+	start TokenPos
+	end   TokenPos
+}
+
+// commentTargets returns the nodes comments can be attached to in pre-order
+func (a *Ast) commentTargets() []commentTarget {
+
+	targets := make([]commentTarget, 0, 16)
+
+	var visit func(n Node)
+	visit = func(n Node) {
+
+		targets = append(targets, commentTarget{node: n, start: n.StartPos(), end: n.EndPos()})
+		switch typedNode := n.(type) {
+
+		case *SelectStmt:
+			for _, e := range typedNode.Es {
+				visit(e)
+			}
+
+		case *BinaryExpr:
+			for _, part := range binaryExprChain(typedNode) {
+				visit(part)
+			}
+
+		case *FuncExpr:
+			for _, arg := range typedNode.Args {
+				visit(arg)
+			}
+
+		case *ObjectLiteralExpr:
+			for i := 0; i < len(typedNode.KeyVals); i++ {
+				visit(&typedNode.KeyVals[i])
+			}
+
+		case *KeyValExpr:
+			visit(typedNode.Val)
+		}
+	}
+
+	for _, n := range a.Nodes {
+		visit(n)
+	}
+
+	return targets
+}
+
+// binaryExprChain returns the parts of a chain of binary expressions with the same operator (e.g. 'a', 'b' and 'c' of "'a' + 'b' + 'c'").
+// A part using another operator (e.g. "'a' + 'b'" of "'a' + 'b' or 'c'") is returned as one part
+func binaryExprChain(e *BinaryExpr) []Expr {
+	return appendBinaryExprChain(make([]Expr, 0, 4), e)
+}
+
+// appendBinaryExprChain appends the parts of the chain to parts, so that long chains aren't copied once per operator
+func appendBinaryExprChain(parts []Expr, e *BinaryExpr) []Expr {
+
+	for _, side := range []Expr{e.Lhs, e.Rhs} {
+
+		if sideBinaryExpr, ok := side.(*BinaryExpr); ok && sideBinaryExpr.Type == e.Type {
+			parts = appendBinaryExprChain(parts, sideBinaryExpr)
+			continue
+		}
+
+		parts = append(parts, side)
+	}
+
+	return parts
+}
+
+// lineOf returns the line of the token that has pos. Tokens are sorted by position, so it is found with a binary search
+func (a *Ast) lineOf(pos TokenPos) int {
+
+	// i is the index of the first token after pos
+	i := sort.Search(len(a.Tokens), func(i int) bool { return a.Tokens[i].Pos > pos })
+	if i == 0 {
+		return 0
+	}
+
+	return a.Tokens[i-1].Line
 }
 
 func (a *Ast) parseFrom(tokenIndex int) (node Node, lastProcessedIndex int, err error) {
@@ -196,6 +417,7 @@ loopLbl:
 				Pos:   t.Pos,
 				Type:  t.Type,
 				Value: t.Val,
+				Raw:   t.Raw,
 			}
 			lastProcessedIndex = i
 			break loopLbl
@@ -213,8 +435,9 @@ loopLbl:
 
 		default:
 			return nil, AST_INVALID_INDEX, &AstError{
-				Err: fmt.Errorf("parseFrom failed because of unhandled token=%+v", t),
+				Err: fmt.Errorf("unexpected %s", describeToken(t)),
 				Pos: t.Pos,
+				End: t.EndPos(),
 			}
 		}
 	}
@@ -224,10 +447,18 @@ loopLbl:
 	}
 
 	// Handle binary ops
-	nextT := a.GetToken(lastProcessedIndex + 1)
-	if nextT != nil && nextT.Type == TokenType_Plus {
+	nextT, nextIndex := a.getNonCommentToken(lastProcessedIndex + 1)
+	if nextT != nil && (nextT.Type == TokenType_Plus || nextT.Type == TokenType_Or) {
 
-		rhs, rhsLastProcessedIndex, err := a.parseFrom(lastProcessedIndex + 2)
+		if rhsT, _ := a.getNonCommentToken(nextIndex + 1); rhsT == nil {
+			return nil, AST_INVALID_INDEX, &AstError{
+				Pos: nextT.Pos,
+				End: nextT.EndPos(),
+				Err: fmt.Errorf("expected an expression after '%s' but reached the end of the query", nextT.Val),
+			}
+		}
+
+		rhs, rhsLastProcessedIndex, err := a.parseFrom(nextIndex + 1)
 		if err != nil {
 			return nil, AST_INVALID_INDEX, err
 		}
@@ -236,16 +467,32 @@ loopLbl:
 		if !ok {
 			return nil, AST_INVALID_INDEX, &AstError{
 				Pos: node.StartPos(),
-				Err: fmt.Errorf("left side of binary operator '+' at pos=%d is not an expression. Lhs=%+v", node.StartPos(), node),
+				End: node.EndPos(),
+				Err: fmt.Errorf("left side of '%s' must be an expression (e.g. a string or a function call)", nextT.Val),
 			}
 		}
 
 		rhsExpr, ok := rhs.(Expr)
 		if !ok {
 			return nil, AST_INVALID_INDEX, &AstError{
-				Pos: node.StartPos(),
-				Err: fmt.Errorf("right side of binary operator '+' at pos=%d is not an expression. Rhs=%+v", rhs.StartPos(), rhs),
+				Pos: rhs.StartPos(),
+				End: rhs.EndPos(),
+				Err: fmt.Errorf("right side of '%s' must be an expression (e.g. a string or a function call)", nextT.Val),
+			}
+		}
+
+		// The right side is parsed first, so "'a' + 'b' or 'c'" gives 'a' + ('b' or 'c'). As '+' binds tighter than 'or',
+		// the '+' is moved into the left side of the 'or' to give ('a' + 'b') or 'c'
+		if rhsOr, ok := rhsExpr.(*BinaryExpr); ok && rhsOr.Type == TokenType_Or && nextT.Type == TokenType_Plus {
+
+			rhsOr.Lhs = &BinaryExpr{
+				Pos:  nextT.Pos,
+				Type: nextT.Type,
+				Lhs:  lhsExpr,
+				Rhs:  rhsOr.Lhs,
 			}
+
+			return rhsOr, rhsLastProcessedIndex, nil
 		}
 
 		return &BinaryExpr{
@@ -271,7 +518,7 @@ func (a *Ast) parseSelect(tokenIndex int) (sStmt *SelectStmt, lastProcessedToken
 
 	if selectToken.Type != TokenType_Keyword || selectToken.Val != "select" {
 		return nil, AST_INVALID_INDEX, &AstError{
-			Err: fmt.Errorf("parseSelect failed because it was invoked on a token at index=%d which is not a select keyword (probably a bug in the code). Token=%+v", tokenIndex, selectToken),
+			Err: fmt.Errorf("parseSelect failed because it was invoked on a token at index=%d which is not a select keyword (probably a bug in the code). Token='%s'", tokenIndex, selectToken.Val),
 		}
 	}
 
@@ -281,13 +528,18 @@ func (a *Ast) parseSelect(tokenIndex int) (sStmt *SelectStmt, lastProcessedToken
 		Es:   make([]Expr, 0, 10),
 	}
 
-	for i := tokenIndex + 1; i < len(a.Tokens); i++ {
+	// An empty select is only reported if it's not because all of its expressions had errors
+	errCountBefore := len(a.errs)
 
-		t := &a.Tokens[i]
+	lastProcessedToken = tokenIndex
+	for i := tokenIndex + 1; i < len(a.Tokens); i++ {
 
 		node, newLastProcessedToken, err := a.parseFrom(i)
 		if err != nil {
-			return nil, AST_INVALID_INDEX, err
+			a.errs.Add(err)
+			lastProcessedToken = a.indexAfterError(err, i) - 1
+			i = lastProcessedToken
+			continue
 		}
 
 		// No error and no node means we are done
@@ -296,17 +548,28 @@ func (a *Ast) parseSelect(tokenIndex int) (sStmt *SelectStmt, lastProcessedToken
 			break
 		}
 
+		lastProcessedToken = newLastProcessedToken
+		i = lastProcessedToken
+
 		expr, ok := node.(Expr)
 		if !ok {
-			return nil, AST_INVALID_INDEX, &AstError{
-				Pos: t.Pos,
-				Err: fmt.Errorf("select has a non-expression (i.e. not a function, not a literal like string etc) in front of it. Token after select=%+v; Node after select=%+v", t, node),
-			}
+			a.errs.Add(&AstError{
+				Pos: node.StartPos(),
+				End: node.EndPos(),
+				Err: fmt.Errorf("select must be followed by expressions (e.g. a string or a function call)"),
+			})
+			continue
 		}
 
 		sStmt.Es = append(sStmt.Es, expr)
-		lastProcessedToken = newLastProcessedToken
-		i = lastProcessedToken
+	}
+
+	if len(sStmt.Es) == 0 && len(a.errs) == errCountBefore {
+		a.errs.Add(&AstError{
+			Pos: selectToken.Pos,
+			End: selectToken.EndPos(),
+			Err: fmt.Errorf("select must be followed by at least one expression (e.g. a string or a function call)"),
+		})
 	}
 
 	return sStmt, lastProcessedToken, nil
@@ -323,7 +586,7 @@ func (a *Ast) parseFunc(tokenIndex int) (fExpr *FuncExpr, lastProcessedToken int
 
 	if funcToken.Type != TokenType_Function_Name {
 		return nil, AST_INVALID_INDEX, &AstError{
-			Err: fmt.Errorf("parseFunc failed because it was invoked on a token at index=%d which is not a function (probably a bug in the code). Token=%+v", tokenIndex, funcToken),
+			Err: fmt.Errorf("parseFunc failed because it was invoked on a token at index=%d which is not a function (probably a bug in the code). Token='%s'", tokenIndex, funcToken.Val),
 		}
 	}
 
@@ -342,10 +605,12 @@ func (a *Ast) parseFunc(tokenIndex int) (fExpr *FuncExpr, lastProcessedToken int
 	if openBracketToken == nil || openBracketToken.Type != TokenType_OpenBracket {
 		return nil, AST_INVALID_INDEX, &AstError{
 			Pos: fExpr.Ident.StartPos(),
-			Err: fmt.Errorf("expected '(' after function name but found token='%+v'", openBracketToken),
+			End: fExpr.Ident.EndPos(),
+			Err: fmt.Errorf("expected '(' after function name '%s' but found %s", fExpr.Ident.Name, describeToken(openBracketToken)),
 		}
 	}
 	fExpr.OpenBracketPos = openBracketToken.Pos
+	lastProcessedToken = tokenIndex + 1
 
 forLoopLbl:
 	for i := tokenIndex + 2; i < len(a.Tokens); i++ {
@@ -359,43 +624,61 @@ forLoopLbl:
 			lastProcessedToken = i
 			break forLoopLbl
 
+		case TokenType_Comment:
+			lastProcessedToken = i
+
 		default:
-			node, lastProcessedToken, err := a.parseFrom(i)
+			node, argLastProcessedToken, err := a.parseFrom(i)
 			if err != nil {
-				return nil, AST_INVALID_INDEX, err
+				a.errs.Add(err)
+				a.incomplete[fExpr] = true
+				i, lastProcessedToken = a.skipListItem(i, TokenType_CloseBracket)
+				continue
 			}
 
+			lastProcessedToken = argLastProcessedToken
 			expr, ok := node.(Expr)
 			if !ok {
-				return nil, AST_INVALID_INDEX, &AstError{
+				a.errs.Add(&AstError{
 					Pos: t.Pos,
-					Err: fmt.Errorf("expected expression to be returned within arguments of a function call, but found node=%+v", node),
-				}
+					End: t.EndPos(),
+					Err: fmt.Errorf("arguments of function '%s' must be expressions (e.g. a string or a function call)", fExpr.Ident.Name),
+				})
+				a.incomplete[fExpr] = true
+				i, lastProcessedToken = a.skipListItem(lastProcessedToken+1, TokenType_CloseBracket)
+				continue
 			}
 
-			// Consume the comma
-			nextT := a.GetToken(lastProcessedToken + 1)
-			if nextT == nil || (nextT.Type != TokenType_Comma && nextT.Type != TokenType_CloseBracket) {
-				return nil, AST_INVALID_INDEX, &AstError{
-					Pos: t.Pos,
-					Err: fmt.Errorf("expected ',' or ')' after function argument at node=%+v pos=%d but found token=%+v", node, t.Pos, nextT),
-				}
+			fExpr.Args = append(fExpr.Args, expr)
+
+			// Consume the comma. The query ending here is reported as the missing closing bracket after the loop
+			nextT, nextIndex := a.getNonCommentToken(lastProcessedToken + 1)
+			if nextT != nil && nextT.Type != TokenType_Comma && nextT.Type != TokenType_CloseBracket {
+				a.errs.Add(unexpectedTokenError(nextT, expr, fmt.Sprintf("expected ',' or ')' after argument of function '%s'", fExpr.Ident.Name)))
+				a.incomplete[fExpr] = true
+				i, lastProcessedToken = a.skipListItem(nextIndex, TokenType_CloseBracket)
+				continue
 			}
 
-			if nextT.Type == TokenType_Comma {
-				lastProcessedToken++
+			if nextT != nil && nextT.Type == TokenType_Comma {
+				lastProcessedToken = nextIndex
 			}
 
-			fExpr.Args = append(fExpr.Args, expr)
 			i = lastProcessedToken
 		}
 	}
 
+	// The function is kept as if it was closed at the end of the query, so the backend still checks the rest of the query
 	if fExpr.CloseBracketPos == AST_INVALID_INDEX {
-		return nil, AST_INVALID_INDEX, &AstError{
-			Pos: funcToken.Pos,
-			Err: fmt.Errorf("function of name=%s at pos=%d does not have a closing bracket", funcToken.Val, funcToken.Pos),
-		}
+
+		a.errs.Add(&AstError{
+			Pos: fExpr.OpenBracketPos,
+			Err: fmt.Errorf("function '%s' does not have a closing bracket", funcToken.Val),
+		})
+
+		a.incomplete[fExpr] = true
+		lastProcessedToken = len(a.Tokens) - 1
+		fExpr.CloseBracketPos = a.Tokens[lastProcessedToken].EndPos() - 1
 	}
 
 	return fExpr, lastProcessedToken, nil
@@ -412,7 +695,7 @@ func (a *Ast) parseObjectLiteral(tokenIndex int) (oLExpr *ObjectLiteralExpr, las
 
 	if oLToken.Type != TokenType_OpenCurlyBracket {
 		return nil, AST_INVALID_INDEX, &AstError{
-			Err: fmt.Errorf("parseObjectLiteral failed because it was invoked on a token at index=%d which is not an object literal (probably a bug in the code). Token=%+v", tokenIndex, oLToken),
+			Err: fmt.Errorf("parseObjectLiteral failed because it was invoked on a token at index=%d which is not an object literal (probably a bug in the code). Token='%s'", tokenIndex, oLToken.Val),
 		}
 	}
 
@@ -422,6 +705,8 @@ func (a *Ast) parseObjectLiteral(tokenIndex int) (oLExpr *ObjectLiteralExpr, las
 		KeyVals:    make([]KeyValExpr, 0, 5),
 	}
 
+	lastProcessedToken = tokenIndex
+
 loopLbl:
 	for i := tokenIndex + 1; i < len(a.Tokens); i++ {
 
@@ -438,36 +723,40 @@ loopLbl:
 
 			colonToken := a.GetToken(i + 1)
 			if colonToken == nil || colonToken.Type != TokenType_Colon {
-				return nil, AST_INVALID_INDEX, &AstError{
+				a.errs.Add(&AstError{
 					Pos: t.Pos,
-					Err: fmt.Errorf("expected colon after object param=%s at pos=%d, but found token=%+v", t.Val, t.Pos, colonToken),
-				}
+					End: t.EndPos(),
+					Err: fmt.Errorf("expected ':' after object key '%s' but found %s", t.Val, describeToken(colonToken)),
+				})
+				i, lastProcessedToken = a.skipListItem(i, TokenType_CloseCurlyBracket)
+				continue
+			}
+
+			if valT, _ := a.getNonCommentToken(i + 2); valT == nil {
+				a.errs.Add(&AstError{
+					Pos: colonToken.Pos,
+					Err: fmt.Errorf("expected a value after ':' of object key '%s' but reached the end of the query", t.Val),
+				})
+				i, lastProcessedToken = a.skipListItem(i, TokenType_CloseCurlyBracket)
+				continue
 			}
 
 			valNode, lastProcessedTokenAfterVal, err := a.parseFrom(i + 2)
 			if err != nil {
-				return nil, AST_INVALID_INDEX, err
+				a.errs.Add(err)
+				i, lastProcessedToken = a.skipListItem(i, TokenType_CloseCurlyBracket)
+				continue
 			}
 
 			valExpr, ok := valNode.(Expr)
 			if !ok {
-				return nil, AST_INVALID_INDEX, &AstError{
-					Pos: t.Pos,
-					Err: fmt.Errorf("expected value of object param=%s at pos=%d to be an experssion, but found node=%+v", t.Val, t.Pos, valNode),
-				}
-			}
-
-			// Consume comma
-			nextT := a.GetToken(lastProcessedTokenAfterVal + 1)
-			if nextT == nil || (nextT.Type != TokenType_Comma && nextT.Type != TokenType_CloseCurlyBracket) {
-				return nil, AST_INVALID_INDEX, &AstError{
+				a.errs.Add(&AstError{
 					Pos: t.Pos,
-					Err: fmt.Errorf("expected ',' or '}' after value of object param=%s at pos=%d, but found token=%+v", t.Val, t.Pos, nextT),
-				}
-			}
-
-			if nextT.Type == TokenType_Comma {
-				lastProcessedTokenAfterVal++
+					End: t.EndPos(),
+					Err: fmt.Errorf("value of object key '%s' must be an expression (e.g. true)", t.Val),
+				})
+				i, lastProcessedToken = a.skipListItem(lastProcessedTokenAfterVal+1, TokenType_CloseCurlyBracket)
+				continue
 			}
 
 			oLExpr.KeyVals = append(oLExpr.KeyVals, KeyValExpr{
@@ -479,6 +768,18 @@ loopLbl:
 				ColonPos: colonToken.Pos,
 			})
 
+			// Consume comma. The query ending here is reported as the missing closing curly bracket after the loop
+			nextT, nextIndex := a.getNonCommentToken(lastProcessedTokenAfterVal + 1)
+			if nextT != nil && nextT.Type != TokenType_Comma && nextT.Type != TokenType_CloseCurlyBracket {
+				a.errs.Add(unexpectedTokenError(nextT, valExpr, fmt.Sprintf("expected ',' or '}' after value of object key '%s'", t.Val)))
+				i, lastProcessedToken = a.skipListItem(nextIndex, TokenType_CloseCurlyBracket)
+				continue
+			}
+
+			if nextT != nil && nextT.Type == TokenType_Comma {
+				lastProcessedTokenAfterVal = nextIndex
+			}
+
 			lastProcessedToken = lastProcessedTokenAfterVal
 			i = lastProcessedToken
 
@@ -486,20 +787,82 @@ loopLbl:
 			lastProcessedToken = i
 
 		default:
-			return nil, AST_INVALID_INDEX, &AstError{
+			a.errs.Add(&AstError{
 				Pos: t.Pos,
-				Err: fmt.Errorf("unexpected token when parsing object params of object=%+v. Unexpected token=%+v", oLExpr, t),
-			}
+				End: t.EndPos(),
+				Err: fmt.Errorf("unexpected %s in object, expected a key (e.g. case_sensitive: true) or '}'", describeToken(t)),
+			})
+			i, lastProcessedToken = a.skipListItem(i, TokenType_CloseCurlyBracket)
 		}
 	}
 
-	if err != nil {
-		return nil, AST_INVALID_INDEX, err
+	if oLExpr.CloseCurly == AST_INVALID_INDEX {
+
+		a.errs.Add(&AstError{
+			Pos: oLExpr.OpenCurly,
+			Err: fmt.Errorf("object does not have a closing curly bracket"),
+		})
+
+		lastProcessedToken = len(a.Tokens) - 1
+		oLExpr.CloseCurly = a.Tokens[lastProcessedToken].EndPos() - 1
 	}
 
 	return oLExpr, lastProcessedToken, nil
 }
 
+// skipListItem is used after an error in a function argument or object key to continue from the next one.
+// It skips tokens starting at index until a ',' or a closing bracket of closeType that is outside any brackets opened while skipping.
+//
+// The returned index is for the loop over the list items, so the loop's i++ moves to the item after a ',' or to the closing bracket.
+// lastProcessedToken is the last skipped token
+func (a *Ast) skipListItem(index int, closeType TokenType) (i, lastProcessedToken int) {
+
+	depth := 0
+	for i = index; i < len(a.Tokens); i++ {
+
+		switch a.Tokens[i].Type {
+
+		case TokenType_OpenBracket, TokenType_OpenCurlyBracket:
+			depth++
+
+		case TokenType_CloseBracket, TokenType_CloseCurlyBracket:
+
+			if depth == 0 && a.Tokens[i].Type == closeType {
+				return i - 1, i - 1
+			}
+
+			depth = max(0, depth-1)
+
+		case TokenType_Comma:
+
+			if depth == 0 {
+				return i, i
+			}
+		}
+	}
+
+	return len(a.Tokens) - 1, len(a.Tokens) - 1
+}
+
+// indexAfterError returns the index of the first token after the part of the query that caused the error, which is where parsing continues after the error.
+// The returned index is always after index so that parsing always moves forward
+func (a *Ast) indexAfterError(err error, index int) int {
+
+	end := TokenPos(AST_INVALID_INDEX)
+
+	var spanErr SpanError
+	if errors.As(err, &spanErr) {
+		_, end = spanErr.Span()
+	}
+
+	nextIndex := index + 1
+	for nextIndex < len(a.Tokens) && a.Tokens[nextIndex].Pos < end {
+		nextIndex++
+	}
+
+	return nextIndex
+}
+
 func (a *Ast) GetToken(index int) *Token {
 
 	if index < 0 {
@@ -513,52 +876,163 @@ func (a *Ast) GetToken(index int) *Token {
 	return &a.Tokens[index]
 }
 
+// unexpectedTokenError returns an error pointing at the unexpected token, or at the node before it if the query ended
+func unexpectedTokenError(t *Token, nodeBefore Node, expected string) *AstError {
+
+	if t == nil {
+		return &AstError{
+			Pos: nodeBefore.StartPos(),
+			End: nodeBefore.EndPos(),
+			Err: fmt.Errorf("%s but reached the end of the query", expected),
+		}
+	}
+
+	return &AstError{
+		Pos: t.Pos,
+		End: t.EndPos(),
+		Err: fmt.Errorf("%s but found %s", expected, describeToken(t)),
+	}
+}
+
+// describeToken returns the token as written in the query and quoted (e.g. "')'"), or "the end of the query" if the token is nil
+func describeToken(t *Token) string {
+
+	switch {
+	case t == nil:
+		return "the end of the query"
+	case t.Type == TokenType_String:
+		return fmt.Sprintf("the string '%s'", t.Val)
+	default:
+		return "'" + t.Val + "'"
+	}
+}
+
+// describeNode returns a short description of the node for error messages (e.g. "the string 'abc'" or "a call to 'starts_with'")
+func describeNode(n Node) string {
+
+	switch typedNode := n.(type) {
+	case *LiteralExpr:
+		if typedNode.Type == TokenType_String {
+			return fmt.Sprintf("the string '%s'", typedNode.Value)
+		}
+		return "'" + typedNode.Value + "'"
+	case *FuncExpr:
+		return fmt.Sprintf("a call to '%s'", typedNode.Ident.Name)
+	case *BinaryExpr:
+		return fmt.Sprintf("a '%s' expression", typedNode.Type.operator())
+	case *ObjectLiteralExpr:
+		return "an object"
+	case *SelectStmt:
+		return "a select"
+	default:
+		return fmt.Sprintf("%T", n)
+	}
+}
+
+// Inspect calls f on n and then on each node inside n in depth-first order, like go/ast.Inspect does.
+// If f returns false the nodes inside the current node are skipped
+func Inspect(n Node, f func(Node) bool) {
+
+	if n == nil || !f(n) {
+		return
+	}
+
+	switch typedNode := n.(type) {
+
+	case *SelectStmt:
+		for _, e := range typedNode.Es {
+			Inspect(e, f)
+		}
+
+	case *BinaryExpr:
+		Inspect(typedNode.Lhs, f)
+		Inspect(typedNode.Rhs, f)
+
+	case *FuncExpr:
+		Inspect(&typedNode.Ident, f)
+		for _, arg := range typedNode.Args {
+			Inspect(arg, f)
+		}
+
+	case *ObjectLiteralExpr:
+		for i := 0; i < len(typedNode.KeyVals); i++ {
+			Inspect(&typedNode.KeyVals[i], f)
+		}
+
+	case *KeyValExpr:
+		Inspect(&typedNode.Key, f)
+		Inspect(typedNode.Val, f)
+	}
+}
+
+// getNonCommentToken returns the first token that isn't a comment starting at index, and the index of that token.
+// nil is returned if there are no such tokens
+func (a *Ast) getNonCommentToken(index int) (*Token, int) {
+
+	for t := a.GetToken(index); t != nil; t = a.GetToken(index) {
+
+		if t.Type != TokenType_Comment {
+			return t, index
+		}
+
+		index++
+	}
+
+	return nil, AST_INVALID_INDEX
+}
+
 func (a *Ast) PrintTree() {
 
 	fmt.Print("\nAST Tree:\n")
+	a.FprintTree(os.Stdout)
+	fmt.Print("\n")
+}
+
+// FprintTree writes the AST as a tree to w, with one node per line
+func (a *Ast) FprintTree(w io.Writer) {
+
 	for i := 0; i < len(a.Nodes); i++ {
-		a.print(a.Nodes[i], 0)
+		a.print(w, a.Nodes[i], 0)
 	}
-	fmt.Print("\n")
 }
 
-func (a *Ast) print(n Node, lvl int) {
+func (a *Ast) print(w io.Writer, n Node, lvl int) {
 
 	switch typedNode := n.(type) {
 
 	case *SelectStmt:
-		a.printStringAtLvl("select", lvl)
+		a.printStringAtLvl(w, "select", lvl)
 
 		for i := 0; i < len(typedNode.Es); i++ {
-			a.print(typedNode.Es[i], lvl+1)
+			a.print(w, typedNode.Es[i], lvl+1)
 		}
 
 	case *BinaryExpr:
-		a.printStringAtLvl(typedNode.Type.String(), lvl)
-		a.print(typedNode.Lhs, lvl+1)
-		a.print(typedNode.Rhs, lvl+1)
+		a.printStringAtLvl(w, typedNode.Type.String(), lvl)
+		a.print(w, typedNode.Lhs, lvl+1)
+		a.print(w, typedNode.Rhs, lvl+1)
 
 	case *FuncExpr:
-		a.printStringAtLvl(typedNode.Ident.Name, lvl)
+		a.printStringAtLvl(w, typedNode.Ident.Name, lvl)
 		for i := 0; i < len(typedNode.Args); i++ {
-			a.print(typedNode.Args[i], lvl+1)
+			a.print(w, typedNode.Args[i], lvl+1)
 		}
 
 	case *IdentExpr:
-		a.printStringAtLvl(typedNode.Name, lvl)
+		a.printStringAtLvl(w, typedNode.Name, lvl)
 
 	case *KeyValExpr:
-		a.printStringAtLvl("key-value pair", lvl)
-		a.print(&typedNode.Key, lvl+1)
-		a.print(typedNode.Val, lvl+1)
+		a.printStringAtLvl(w, "key-value pair", lvl)
+		a.print(w, &typedNode.Key, lvl+1)
+		a.print(w, typedNode.Val, lvl+1)
 
 	case *LiteralExpr:
-		a.printStringAtLvl(typedNode.Value, lvl)
+		a.printStringAtLvl(w, typedNode.Value, lvl)
 
 	case *ObjectLiteralExpr:
-		a.printStringAtLvl("object", lvl)
+		a.printStringAtLvl(w, "object", lvl)
 		for i := 0; i < len(typedNode.KeyVals); i++ {
-			a.print(&typedNode.KeyVals[i], lvl+1)
+			a.print(w, &typedNode.KeyVals[i], lvl+1)
 		}
 
 	default:
@@ -566,7 +1040,7 @@ func (a *Ast) print(n Node, lvl int) {
 	}
 }
 
-func (a *Ast) printStringAtLvl(s string, lvl int) {
+func (a *Ast) printStringAtLvl(w io.Writer, s string, lvl int) {
 
 	finalString := "|"
 	for i := 0; i < lvl; i++ {
@@ -574,5 +1048,5 @@ func (a *Ast) printStringAtLvl(s string, lvl int) {
 	}
 
 	finalString += "-- " + s + "\n"
-	fmt.Print(finalString)
+	fmt.Fprint(w, finalString)
 }
@@ -0,0 +1,481 @@
+package regexl
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// ExprType is the type of an expression, which decides where the expression can be used (e.g. a from_to call can only be passed to any_chars_of)
+type ExprType int
+
+const (
+	// ExprType_Unknown is the type of expressions that couldn't be checked (e.g. a call to an unknown function).
+	// They are accepted everywhere so that one problem isn't reported multiple times
+	ExprType_Unknown ExprType = iota
+	// ExprType_Pattern is the type of expressions that match text (e.g. 'abc' or one_plus_of('a'))
+	ExprType_Pattern
+	// ExprType_CharSet is the type of expressions that match one character of a set (e.g. any_chars_of('abc')). They can be used wherever a pattern can
+	ExprType_CharSet
+	// ExprType_CharRange is the type of ranges of characters (e.g. from_to('a', 'z')), which can only be used to build a character set
+	ExprType_CharRange
+	// ExprType_CharClass is the type of predefined classes of characters (e.g. digit() or unicode_category('Lu')), which can be used wherever a pattern can and to build a character set
+	ExprType_CharClass
+	ExprType_Int
+	ExprType_Float
+	ExprType_Bool
+	ExprType_Object
+)
+
+func (t ExprType) String() string {
+
+	switch t {
+	case ExprType_Pattern:
+		return "pattern"
+	case ExprType_CharSet:
+		return "char-set"
+	case ExprType_CharRange:
+		return "char-range"
+	case ExprType_CharClass:
+		return "char-class"
+	case ExprType_Int:
+		return "integer"
+	case ExprType_Float:
+		return "float"
+	case ExprType_Bool:
+		return "bool"
+	case ExprType_Object:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// accepts returns true if an expression of type other can be used where an expression of type t is needed
+func (t ExprType) accepts(other ExprType) bool {
+	return t == other || (t == ExprType_Pattern && (other == ExprType_CharSet || other == ExprType_CharClass))
+}
+
+// param is what a function accepts as one of its arguments
+type param struct {
+	// Name is the name of the argument as used in BuiltinFuncs (e.g. 'min' of count_between)
+	Name string
+	// Desc describes what the argument must be for error messages
+	Desc string
+	// Types are the accepted types
+	Types []ExprType
+	// LiteralTypes are types that are only accepted when the argument is a literal (e.g. the capture name of capture_as must be a string and not any pattern)
+	LiteralTypes []ExprType
+	// IsChar means literals must hold exactly one character (e.g. 'a' or 0)
+	IsChar bool
+}
+
+// funcSignature describes the arguments and result of a built-in function
+type funcSignature struct {
+	Params []param
+	// IsVariadic means the last param can be repeated, so the function takes at least len(Params) arguments
+	IsVariadic bool
+	Result     ExprType
+}
+
+var (
+	patternParam = param{
+		Name:  "x",
+		Desc:  "a pattern (e.g. 'abc' or one_plus_of('a'))",
+		Types: []ExprType{ExprType_Pattern},
+	}
+
+	charsParam = param{
+		Name:         "chars",
+		Desc:         "characters (e.g. 'abc'), a from_to call or a character class (e.g. digit() or unicode_category('Lu'))",
+		Types:        []ExprType{ExprType_CharRange, ExprType_CharClass},
+		LiteralTypes: []ExprType{ExprType_Pattern, ExprType_Int},
+	}
+
+	captureNameParam = param{
+		Name:         "name",
+		Desc:         "a string holding the capture name (e.g. 'year')",
+		LiteralTypes: []ExprType{ExprType_Pattern},
+	}
+
+	optionsParam = param{
+		Name:  "options",
+		Desc:  "an object of options (e.g. {case_sensitive: true})",
+		Types: []ExprType{ExprType_Object},
+	}
+
+	unicodeClassParam = param{
+		Name:  "class",
+		Desc:  "a call to unicode_category or unicode_script",
+		Types: []ExprType{ExprType_CharClass},
+	}
+)
+
+func charParam(name string) param {
+	return param{
+		Name:         name,
+		Desc:         "a literal of exactly one character (e.g. 'a' or 0)",
+		LiteralTypes: []ExprType{ExprType_Pattern, ExprType_Int},
+		IsChar:       true,
+	}
+}
+
+// unicodeNameParam is the name of a unicode category or script, where kind is 'category' or 'script'
+func unicodeNameParam(kind, example string) param {
+	return param{
+		Name:         "name",
+		Desc:         fmt.Sprintf("a string holding the name of a unicode %s (e.g. '%s')", kind, example),
+		LiteralTypes: []ExprType{ExprType_Pattern},
+	}
+}
+
+func intParam(name string) param {
+	return param{
+		Name:  name,
+		Desc:  "an integer (e.g. 10)",
+		Types: []ExprType{ExprType_Int},
+	}
+}
+
+// builtinSignatures are the signatures of the functions in BuiltinFuncs, except set_options which the checker handles on its own.
+// The options object of with_options is checked like the one of set_options after its signature is checked
+var builtinSignatures = map[string]funcSignature{
+	"any_strings_of":           {Params: []param{patternParam}, IsVariadic: true, Result: ExprType_Pattern},
+	"any_chars_of":             {Params: []param{charsParam}, IsVariadic: true, Result: ExprType_CharSet},
+	"none_of_chars":            {Params: []param{charsParam}, IsVariadic: true, Result: ExprType_CharSet},
+	"from_to":                  {Params: []param{charParam("from"), charParam("to")}, Result: ExprType_CharRange},
+	"starts_with":              {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"ends_with":                {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"line_starts_with":         {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"line_ends_with":           {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"text_starts_with":         {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"text_ends_with":           {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"any_chars":                {Result: ExprType_Pattern},
+	"any_char":                 {Result: ExprType_CharSet},
+	"digit":                    {Result: ExprType_CharClass},
+	"not_digit":                {Result: ExprType_CharClass},
+	"letter":                   {Result: ExprType_CharClass},
+	"not_letter":               {Result: ExprType_CharClass},
+	"whitespace":               {Result: ExprType_CharClass},
+	"not_whitespace":           {Result: ExprType_CharClass},
+	"word_char":                {Result: ExprType_CharClass},
+	"not_word_char":            {Result: ExprType_CharClass},
+	"unicode_category":         {Params: []param{unicodeNameParam("category", "Lu")}, Result: ExprType_CharClass},
+	"unicode_script":           {Params: []param{unicodeNameParam("script", "Greek")}, Result: ExprType_CharClass},
+	"not_unicode":              {Params: []param{unicodeClassParam}, Result: ExprType_CharClass},
+	"zero_plus_of":             {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"one_plus_of":              {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"count_between":            {Params: []param{patternParam, intParam("min"), intParam("max")}, Result: ExprType_Pattern},
+	"optional":                 {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"exactly":                  {Params: []param{patternParam, intParam("n")}, Result: ExprType_Pattern},
+	"at_least":                 {Params: []param{patternParam, intParam("n")}, Result: ExprType_Pattern},
+	"at_most":                  {Params: []param{patternParam, intParam("n")}, Result: ExprType_Pattern},
+	"capture":                  {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"capture_as":               {Params: []param{captureNameParam, patternParam}, Result: ExprType_Pattern},
+	"followed_by":              {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"not_followed_by":          {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"preceded_by":              {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"not_preceded_by":          {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"same_as_capture":          {Params: []param{captureNameParam}, Result: ExprType_Pattern},
+	"atomic":                   {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"possessive_zero_plus_of":  {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"possessive_one_plus_of":   {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"possessive_count_between": {Params: []param{patternParam, intParam("min"), intParam("max")}, Result: ExprType_Pattern},
+	"lazy_zero_plus_of":        {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"lazy_one_plus_of":         {Params: []param{patternParam}, Result: ExprType_Pattern},
+	"lazy_count_between":       {Params: []param{patternParam, intParam("min"), intParam("max")}, Result: ExprType_Pattern},
+	"with_options":             {Params: []param{optionsParam, patternParam}, Result: ExprType_Pattern},
+}
+
+// optionTypes are the types of the values of the options in BuiltinOptions
+var optionTypes = map[string]ExprType{
+	"case_sensitive":      ExprType_Bool,
+	"find_all_matches":    ExprType_Bool,
+	"ungreedy":            ExprType_Bool,
+	"multiline":           ExprType_Bool,
+	"dot_matches_newline": ExprType_Bool,
+}
+
+var _ error = &CheckError{}
+
+// CheckError is an error found by the Checker about an expression used where its type isn't allowed
+type CheckError struct {
+	Err error
+	Pos TokenPos
+	// End is the position after the last byte of what caused the error. If End isn't after Pos the error is about the character at Pos
+	End TokenPos
+
+	// query is set by setErrorsQuery
+	query string
+}
+
+func (ce *CheckError) Span() (start, end TokenPos) {
+	return errorSpan(ce.Pos, ce.End)
+}
+
+func (ce *CheckError) Unwrap() error {
+	return ce.Err
+}
+
+func (ce *CheckError) setQuery(query string) {
+	ce.query = query
+}
+
+func (ce *CheckError) Error() string {
+
+	if ce == nil || ce.Err == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("check error: loc=%s; err=%s", errorLoc(ce.query, ce.Pos), ce.Err.Error())
+}
+
+// Checker runs between Ast.Gen and the backend, and checks that functions are known, are passed the right number of arguments,
+// and that every expression is only used where its type is allowed (e.g. from_to only inside any_chars_of), so that such queries
+// are reported instead of producing broken regexes
+type Checker struct {
+	Ast *Ast
+	// Types are the types of the expressions checked so far
+	Types map[Expr]ExprType
+
+	errs ErrorList
+}
+
+func NewChecker(ast *Ast) *Checker {
+	return &Checker{
+		Ast:   ast,
+		Types: map[Expr]ExprType{},
+	}
+}
+
+// Check checks all the nodes of the AST and returns an ErrorList with all the problems found
+func (c *Checker) Check() error {
+
+	c.errs = ErrorList{}
+
+	for _, n := range c.Ast.Nodes {
+
+		switch typedNode := n.(type) {
+
+		case *SelectStmt:
+			for _, e := range typedNode.Es {
+				c.checkPattern(e, "select expressions")
+			}
+
+		case *FuncExpr:
+
+			if typedNode.Ident.Name != "set_options" {
+				c.addError(typedNode, "only the function 'set_options' can be used at the top level")
+				continue
+			}
+
+			c.checkSetOptions(typedNode)
+
+		default:
+			c.addError(n, "only 'select' and the 'set_options' function can be at the top level")
+		}
+	}
+
+	return c.errs.Err()
+}
+
+// checkExpr returns the type of the expression after checking it and everything inside it
+func (c *Checker) checkExpr(e Expr) (t ExprType) {
+
+	defer func() {
+		c.Types[e] = t
+	}()
+
+	switch typedExpr := e.(type) {
+
+	case *LiteralExpr:
+
+		switch typedExpr.Type {
+		case TokenType_String:
+			return ExprType_Pattern
+		case TokenType_Int:
+			return ExprType_Int
+		case TokenType_Float:
+			return ExprType_Float
+		case TokenType_Bool:
+			return ExprType_Bool
+		default:
+			return ExprType_Unknown
+		}
+
+	case *BinaryExpr:
+		context := fmt.Sprintf("both sides of '%s'", typedExpr.Type.operator())
+		c.checkPattern(typedExpr.Lhs, context)
+		c.checkPattern(typedExpr.Rhs, context)
+		return ExprType_Pattern
+
+	case *ObjectLiteralExpr:
+		return ExprType_Object
+
+	case *FuncExpr:
+		return c.checkFunc(typedExpr)
+
+	default:
+		return ExprType_Unknown
+	}
+}
+
+// checkPattern checks that the expression is a pattern, where what is the place the expression is used in for the error message (e.g. "select expressions")
+func (c *Checker) checkPattern(e Expr, what string) {
+
+	t := c.checkExpr(e)
+	if t == ExprType_Unknown || ExprType_Pattern.accepts(t) {
+		return
+	}
+
+	c.addError(e, "%s must be patterns (e.g. 'abc' or one_plus_of('a')), but found %s of type %s", what, describeNode(e), t)
+}
+
+func (c *Checker) checkFunc(fExpr *FuncExpr) ExprType {
+
+	if fExpr.Ident.Name == "set_options" {
+		c.addError(fExpr, "the function 'set_options' can only be used at the top level")
+		return ExprType_Unknown
+	}
+
+	sig, ok := builtinSignatures[fExpr.Ident.Name]
+	if !ok {
+
+		c.addError(fExpr, "trying to call unknown function '%s'", fExpr.Ident.Name)
+		for _, arg := range fExpr.Args {
+			c.checkExpr(arg)
+		}
+
+		return ExprType_Unknown
+	}
+
+	// Arguments of incomplete calls might be missing, so they are checked without knowing which param they are for
+	if c.Ast.IsIncomplete(fExpr) {
+
+		for _, arg := range fExpr.Args {
+			c.checkExpr(arg)
+		}
+
+		return sig.Result
+	}
+
+	argCountOk := len(fExpr.Args) == len(sig.Params) || (sig.IsVariadic && len(fExpr.Args) >= len(sig.Params))
+	if !argCountOk {
+		c.addError(fExpr, "function '%s' must have %s but was passed %d arguments", fExpr.Ident.Name, sig.argCountText(), len(fExpr.Args))
+	}
+
+	for i, arg := range fExpr.Args {
+
+		if i >= len(sig.Params) && !sig.IsVariadic {
+			c.checkExpr(arg)
+			continue
+		}
+
+		c.checkArg(fExpr, arg, sig.Params[min(i, len(sig.Params)-1)])
+	}
+
+	if fExpr.Ident.Name == "with_options" && len(fExpr.Args) > 0 {
+
+		if oLExpr, ok := fExpr.Args[0].(*ObjectLiteralExpr); ok {
+			c.checkOptions(fExpr, oLExpr)
+		}
+	}
+
+	return sig.Result
+}
+
+func (c *Checker) checkArg(fExpr *FuncExpr, arg Expr, p param) {
+
+	t := c.checkExpr(arg)
+	if t == ExprType_Unknown {
+		return
+	}
+
+	lit, isLit := arg.(*LiteralExpr)
+	isAccepted := slices.ContainsFunc(p.Types, func(pt ExprType) bool { return pt.accepts(t) })
+	if isLit && slices.Contains(p.LiteralTypes, t) {
+		isAccepted = !p.IsChar || utf8.RuneCountInString(lit.Value) == 1
+	}
+
+	if !isAccepted {
+		c.addError(arg, "argument '%s' of function '%s' must be %s, but found %s of type %s", p.Name, fExpr.Ident.Name, p.Desc, describeNode(arg), t)
+	}
+}
+
+// checkSetOptions checks that set_options is passed objects whose keys are known options with values of the right type
+func (c *Checker) checkSetOptions(fExpr *FuncExpr) {
+
+	for _, arg := range fExpr.Args {
+
+		t := c.checkExpr(arg)
+		oLExpr, ok := arg.(*ObjectLiteralExpr)
+		if !ok {
+
+			if t != ExprType_Unknown {
+				c.addError(arg, "only one passed object (e.g. {case_sensitive:true}) is allowed as input to the function %s", fExpr.Ident.Name)
+			}
+
+			continue
+		}
+
+		c.checkOptions(fExpr, oLExpr)
+	}
+}
+
+// checkOptions checks that the keys of the options object passed to fExpr (e.g. set_options) are known options with values of the right type.
+// find_all_matches applies to the whole query, so it is only allowed in set_options
+func (c *Checker) checkOptions(fExpr *FuncExpr, oLExpr *ObjectLiteralExpr) {
+
+	for i := 0; i < len(oLExpr.KeyVals); i++ {
+
+		kva := &oLExpr.KeyVals[i]
+		valType := c.checkExpr(kva.Val)
+
+		expectedType, ok := optionTypes[kva.Key.Name]
+		if !ok {
+			c.addError(kva, "unknown parameter '%s' in the function %s", kva.Key.Name, fExpr.Ident.Name)
+			continue
+		}
+
+		if kva.Key.Name == "find_all_matches" && fExpr.Ident.Name != "set_options" {
+			c.addError(kva, "option 'find_all_matches' applies to the whole query, so it can only be set with the function set_options")
+			continue
+		}
+
+		if _, isLit := kva.Val.(*LiteralExpr); !isLit || valType != expectedType {
+			c.addError(kva, "value of option '%s' must be a %s literal (e.g. %s), but found %s", kva.Key.Name, expectedType, exampleOf(expectedType), describeNode(kva.Val))
+		}
+	}
+}
+
+// argCountText returns the number of arguments the function takes for error messages (e.g. "two arguments")
+func (sig funcSignature) argCountText() string {
+
+	count := []string{"no arguments", "one argument", "two arguments", "three arguments"}[len(sig.Params)]
+	if sig.IsVariadic {
+		return "at least " + count
+	}
+
+	return count
+}
+
+// exampleOf returns an example value of the type for error messages
+func exampleOf(t ExprType) string {
+
+	switch t {
+	case ExprType_Bool:
+		return "true"
+	case ExprType_Int:
+		return "10"
+	default:
+		return "'abc'"
+	}
+}
+
+func (c *Checker) addError(n Node, format string, args ...any) {
+	c.errs.Add(&CheckError{
+		Err: fmt.Errorf(format, args...),
+		Pos: n.StartPos(),
+		End: n.EndPos(),
+	})
+}
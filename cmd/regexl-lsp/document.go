@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bloeys/regexl"
+)
+
+// document is an open .regexl file and the result of tokenizing and parsing it.
+// Tokens are kept even if parsing fails, as completion is mostly needed while the query is incomplete
+type document struct {
+	URI  string
+	Text string
+
+	Tokens []regexl.Token
+	// Ast has the nodes that could be parsed even if Err is set, and is only nil for an empty document
+	Ast *regexl.Ast
+	Err error
+
+	// lineStarts are the byte offsets at which each line starts
+	lineStarts []int
+}
+
+func newDocument(uri, text string) *document {
+
+	doc := &document{
+		URI:        uri,
+		Text:       text,
+		lineStarts: []int{0},
+	}
+
+	for i := 0; i < len(text); i++ {
+
+		if text[i] == '\n' {
+			doc.lineStarts = append(doc.lineStarts, i+1)
+		}
+	}
+
+	doc.Ast, doc.Err = regexl.NewRegexl(text).Parse()
+	if doc.Ast != nil {
+		doc.Tokens = doc.Ast.Tokens
+	}
+
+	return doc
+}
+
+// position converts a byte offset of the text into an LSP position
+func (d *document) position(offset int) position {
+
+	offset = max(0, min(offset, len(d.Text)))
+
+	line := 0
+	for line+1 < len(d.lineStarts) && d.lineStarts[line+1] <= offset {
+		line++
+	}
+
+	character := 0
+	for _, r := range d.Text[d.lineStarts[line]:offset] {
+		character += utf16Len(r)
+	}
+
+	return position{Line: line, Character: character}
+}
+
+// offset converts an LSP position into a byte offset of the text
+func (d *document) offset(pos position) int {
+
+	if pos.Line < 0 {
+		return 0
+	}
+
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.Text)
+	}
+
+	offset := d.lineStarts[pos.Line]
+	for character := 0; character < pos.Character && offset < len(d.Text); {
+
+		r, size := utf8.DecodeRuneInString(d.Text[offset:])
+		if r == '\n' {
+			break
+		}
+
+		character += utf16Len(r)
+		offset += size
+	}
+
+	return offset
+}
+
+func (d *document) rangeOf(start, end int) lspRange {
+	return lspRange{Start: d.position(start), End: d.position(end)}
+}
+
+// tokenAt returns the token that has the offset, or that ends at the offset so that the cursor being right after a word counts as being on it
+func (d *document) tokenAt(offset int) *regexl.Token {
+
+	for i := 0; i < len(d.Tokens); i++ {
+
+		t := &d.Tokens[i]
+		if int(t.Pos) <= offset && offset <= int(t.EndPos()) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// errorRange returns the range and message of a Regexl error. Errors without a span are put at the start of the document
+func (d *document) errorRange(err error) (lspRange, string) {
+
+	var spanErr regexl.SpanError
+	if !errors.As(err, &spanErr) {
+		return d.rangeOf(0, 0), err.Error()
+	}
+
+	start, end := spanErr.Span()
+	return d.rangeOf(int(start), int(end)), spanErr.Unwrap().Error()
+}
+
+// innermostBracket returns the type of the innermost bracket that is open at the offset, or TokenType_Unknown if there is none
+func (d *document) innermostBracket(offset int) regexl.TokenType {
+
+	openBrackets := make([]regexl.TokenType, 0, 4)
+	for _, t := range d.Tokens {
+
+		if int(t.Pos) >= offset {
+			break
+		}
+
+		switch t.Type {
+		case regexl.TokenType_OpenBracket, regexl.TokenType_OpenCurlyBracket:
+			openBrackets = append(openBrackets, t.Type)
+		case regexl.TokenType_CloseBracket, regexl.TokenType_CloseCurlyBracket:
+			if len(openBrackets) > 0 {
+				openBrackets = openBrackets[:len(openBrackets)-1]
+			}
+		}
+	}
+
+	if len(openBrackets) == 0 {
+		return regexl.TokenType_Unknown
+	}
+
+	return openBrackets[len(openBrackets)-1]
+}
+
+// funcAt returns the function call whose name is at the offset
+func (d *document) funcAt(offset int) *regexl.FuncExpr {
+
+	if d.Ast == nil {
+		return nil
+	}
+
+	var fExpr *regexl.FuncExpr
+	for _, n := range d.Ast.Nodes {
+
+		regexl.Inspect(n, func(n regexl.Node) bool {
+
+			if f, ok := n.(*regexl.FuncExpr); ok && int(f.Ident.StartPos()) <= offset && offset <= int(f.Ident.EndPos()) {
+				fExpr = f
+			}
+
+			return fExpr == nil
+		})
+	}
+
+	return fExpr
+}
+
+// findFuncs returns all calls of the function of the passed name
+func (d *document) findFuncs(name string) []*regexl.FuncExpr {
+
+	fExprs := make([]*regexl.FuncExpr, 0)
+	if d.Ast == nil {
+		return fExprs
+	}
+
+	for _, n := range d.Ast.Nodes {
+
+		regexl.Inspect(n, func(n regexl.Node) bool {
+
+			if f, ok := n.(*regexl.FuncExpr); ok && f.Ident.Name == name {
+				fExprs = append(fExprs, f)
+			}
+
+			return true
+		})
+	}
+
+	return fExprs
+}
+
+// stringArg returns the argument of the function at index if it is a string literal
+func stringArg(fExpr *regexl.FuncExpr, index int) (*regexl.LiteralExpr, bool) {
+
+	if index >= len(fExpr.Args) {
+		return nil, false
+	}
+
+	lit, ok := fExpr.Args[index].(*regexl.LiteralExpr)
+	return lit, ok && lit.Type == regexl.TokenType_String
+}
+
+func utf16Len(r rune) int {
+
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+// codeBlock returns s as a markdown code block
+func codeBlock(lang, s string) string {
+	return "```" + lang + "\n" + strings.TrimSuffix(s, "\n") + "\n```"
+}
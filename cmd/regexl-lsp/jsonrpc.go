@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by LSP
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// message is a JSON-RPC request or notification. Notifications have no Id
+type message struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (m *message) IsNotification() bool {
+	return len(m.Id) == 0
+}
+
+type response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	// Result is always set on success, even if it is 'null', and never set on error
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *responseError  `json:"error,omitempty"`
+}
+
+type notification struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (re *responseError) Error() string {
+	return fmt.Sprintf("code=%d; msg=%s", re.Code, re.Message)
+}
+
+// conn reads and writes JSON-RPC messages framed by a 'Content-Length' header, as LSP does over stdio
+type conn struct {
+	r *textproto.Reader
+
+	writeLock sync.Mutex
+	w         io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		r: textproto.NewReader(bufio.NewReader(r)),
+		w: w,
+	}
+}
+
+func (c *conn) Read() (*message, error) {
+
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	contentLen, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header. Header=%v; Err=%s", header, err)
+	}
+
+	body := make([]byte, contentLen)
+	_, err = io.ReadFull(c.r.R, body)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &message{}
+	err = json.Unmarshal(body, msg)
+	if err != nil {
+		return nil, &responseError{Code: codeParseError, Message: err.Error()}
+	}
+
+	return msg, nil
+}
+
+func (c *conn) Reply(id json.RawMessage, result any, err error) error {
+
+	resp := &response{
+		Jsonrpc: "2.0",
+		Id:      id,
+	}
+
+	if err != nil {
+
+		respErr, ok := err.(*responseError)
+		if !ok {
+			respErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+
+		resp.Error = respErr
+		return c.write(resp)
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	resp.Result = resultBytes
+	return c.write(resp)
+}
+
+func (c *conn) Notify(method string, params any) error {
+	return c.write(&notification{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (c *conn) write(v any) error {
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,34 @@
+// Command regexl-lsp is a language server for Regexl (.regexl) files, which talks LSP over stdin and stdout.
+//
+// It provides diagnostics, hover docs for built-in functions and set_options keys, the regex produced by a function call or
+// by the whole query (when hovering 'select'), completion of function names and set_options keys, and go-to-definition from
+// same_as_capture('name') to the capture_as that defines 'name'.
+//
+// The regex dialect used for diagnostics and hovers is set with -dialect, or with the 'dialect' initialization option.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bloeys/regexl"
+)
+
+func main() {
+
+	dialect := flag.String("dialect", regexl.Dialect_Go, "the regex dialect queries are compiled to. One of: "+strings.Join(regexl.Dialects(), ", "))
+	flag.Parse()
+
+	// Stdout is used by the protocol, so logs go to stderr which editors usually show in their LSP logs
+	log.SetOutput(os.Stderr)
+	log.SetPrefix("regexl-lsp: ")
+
+	err := newServer(newConn(os.Stdin, os.Stdout), *dialect).Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "regexl-lsp: %s\n", err)
+		os.Exit(1)
+	}
+}
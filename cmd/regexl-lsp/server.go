@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/bloeys/regexl"
+)
+
+// errExit is returned by server.Handle when the client asked the server to exit
+var errExit = errors.New("exit")
+
+type server struct {
+	conn    *conn
+	dialect string
+	docs    map[string]*document
+
+	isShutdown bool
+}
+
+func newServer(c *conn, dialect string) *server {
+	return &server{
+		conn:    c,
+		dialect: dialect,
+		docs:    map[string]*document{},
+	}
+}
+
+// Run handles messages until the client asks the server to exit or the connection is closed
+func (s *server) Run() error {
+
+	for {
+
+		msg, err := s.conn.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		result, err := s.Handle(msg)
+		if errors.Is(err, errExit) {
+
+			if !s.isShutdown {
+				return fmt.Errorf("client asked to exit before asking to shutdown")
+			}
+
+			return nil
+		}
+
+		if msg.IsNotification() {
+
+			if err != nil {
+				log.Printf("handling notification '%s' failed. Err=%s\n", msg.Method, err)
+			}
+
+			continue
+		}
+
+		err = s.conn.Reply(msg.Id, result, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) Handle(msg *message) (result any, err error) {
+
+	switch msg.Method {
+
+	case "initialize":
+
+		params := &initializeParams{}
+		err = unmarshalParams(msg.Params, params)
+		if err != nil {
+			return nil, err
+		}
+
+		if params.InitializationOptions.Dialect != "" {
+			s.dialect = params.InitializationOptions.Dialect
+		}
+
+		if _, err := regexl.NewBackend(s.dialect); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		initResult := &initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				HoverProvider:      true,
+				CompletionProvider: map[string]any{},
+				DefinitionProvider: true,
+			},
+		}
+		initResult.ServerInfo.Name = "regexl-lsp"
+		return initResult, nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.isShutdown = true
+		return nil, nil
+
+	case "exit":
+		return nil, errExit
+
+	case "textDocument/didOpen":
+
+		params := &didOpenParams{}
+		err = unmarshalParams(msg.Params, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+
+		params := &didChangeParams{}
+		err = unmarshalParams(msg.Params, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// With full sync the last change has the whole text
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+
+		params := &didCloseParams{}
+		err = unmarshalParams(msg.Params, params)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.conn.Notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+
+	case "textDocument/hover", "textDocument/completion", "textDocument/definition":
+
+		params := &textDocumentPositionParams{}
+		err = unmarshalParams(msg.Params, params)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document '%s' isn't open", params.TextDocument.URI)}
+		}
+
+		offset := doc.offset(params.Position)
+		switch msg.Method {
+		case "textDocument/hover":
+			return s.hover(doc, offset), nil
+		case "textDocument/completion":
+			return s.completion(doc, offset), nil
+		default:
+			return s.definition(doc, offset), nil
+		}
+
+	default:
+
+		// Notifications we don't handle (e.g. '$/cancelRequest') are ignored as the spec says
+		if msg.IsNotification() {
+			return nil, nil
+		}
+
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method '%s' isn't supported", msg.Method)}
+	}
+}
+
+// update parses the new text of the document and publishes its diagnostics
+func (s *server) update(uri, text string) error {
+
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+
+	return s.conn.Notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: s.diagnostics(doc),
+	})
+}
+
+func (s *server) diagnostics(doc *document) []diagnostic {
+
+	diags := make([]diagnostic, 0, 1)
+
+	// Compiling parses again, but also finds the backend errors of the parts that could be parsed
+	rl := regexl.NewRegexl(doc.Text)
+	err := rl.CompileFor(s.dialect)
+	if err != nil {
+
+		for _, e := range regexl.Errors(err) {
+
+			r, msg := doc.errorRange(e)
+			diags = append(diags, diagnostic{
+				Range:    r,
+				Severity: severityError,
+				Source:   "regexl",
+				Message:  msg,
+			})
+		}
+
+		return diags
+	}
+
+	for _, d := range rl.Diagnostics {
+
+		start := max(0, int(d.Pos))
+		diags = append(diags, diagnostic{
+			Range:    doc.rangeOf(start, start+1),
+			Severity: severityInformation,
+			Source:   "regexl",
+			Message:  d.Msg,
+		})
+	}
+
+	return diags
+}
+
+// hover shows the docs of built-in functions and set_options keys, and the regex produced by function calls and the whole query (when on 'select')
+func (s *server) hover(doc *document, offset int) *hover {
+
+	t := doc.tokenAt(offset)
+	if t == nil {
+		return nil
+	}
+
+	sections := make([]string, 0, 2)
+	switch t.Type {
+
+	case regexl.TokenType_Function_Name:
+
+		builtin, ok := regexl.LookupBuiltin(regexl.BuiltinFuncs, t.Val)
+		if !ok {
+			sections = append(sections, fmt.Sprintf("Unknown function '%s'", t.Val))
+			break
+		}
+
+		sections = append(sections, codeBlock("regexl", builtin.Signature), builtin.Doc)
+
+		// set_options can't be compiled on its own
+		if fExpr := doc.funcAt(offset); fExpr != nil && fExpr.Ident.Name != "set_options" {
+
+			if regexString, err := s.compileExpr(doc, fExpr); err == nil {
+				sections = append(sections, fmt.Sprintf("Compiles to (%s):\n\n%s", s.dialect, codeBlock("regex", regexString)))
+			}
+		}
+
+	case regexl.TokenType_Object_Param:
+
+		builtin, ok := regexl.LookupBuiltin(regexl.BuiltinOptions, t.Val)
+		if !ok {
+			sections = append(sections, fmt.Sprintf("Unknown option '%s'", t.Val))
+			break
+		}
+
+		sections = append(sections, codeBlock("regexl", builtin.Signature), builtin.Doc)
+
+	case regexl.TokenType_Keyword:
+
+		if doc.Err != nil {
+			sections = append(sections, "The query has errors, so it can't be compiled")
+			break
+		}
+
+		regexString, err := regexl.NewRegexl(doc.Text).CompileString(s.dialect)
+		if err != nil {
+			sections = append(sections, "The query can't be compiled: "+err.Error())
+			break
+		}
+
+		sections = append(sections, fmt.Sprintf("Compiles to (%s):\n\n%s", s.dialect, codeBlock("regex", regexString)))
+
+	default:
+		return nil
+	}
+
+	r := doc.rangeOf(int(t.Pos), int(t.EndPos()))
+	return &hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: strings.Join(sections, "\n\n"),
+		},
+		Range: &r,
+	}
+}
+
+// compileExpr checks and compiles the expression as if it was the only thing selected, while keeping the options of the query
+func (s *server) compileExpr(doc *document, e regexl.Expr) (string, error) {
+
+	ast := &regexl.Ast{
+		Tokens: doc.Ast.Tokens,
+		Nodes:  make([]regexl.Node, 0, 2),
+	}
+
+	for _, n := range doc.Ast.Nodes {
+
+		if fExpr, ok := n.(*regexl.FuncExpr); ok && fExpr.Ident.Name == "set_options" {
+			ast.Nodes = append(ast.Nodes, n)
+		}
+	}
+
+	ast.Nodes = append(ast.Nodes, &regexl.SelectStmt{Type: regexl.TokenType_Keyword, Es: []regexl.Expr{e}})
+
+	// The backend doesn't check types, so expressions the checker rejects (e.g. from_to outside any_chars_of) would show a broken regex
+	err := regexl.NewChecker(ast).Check()
+	if err != nil {
+		return "", err
+	}
+
+	backend, err := regexl.NewBackend(s.dialect)
+	if err != nil {
+		return "", err
+	}
+
+	regexString, _, err := backend.AstToRegexString(ast)
+	return regexString, err
+}
+
+// completion completes set_options keys inside objects, and function names and the select keyword everywhere else
+func (s *server) completion(doc *document, offset int) []completionItem {
+
+	items := make([]completionItem, 0, len(regexl.BuiltinFuncs)+1)
+
+	// Strings and comments are free text
+	if t := doc.tokenAt(offset); t != nil && (t.Type == regexl.TokenType_String || t.Type == regexl.TokenType_Comment) && offset > int(t.Pos) && offset < int(t.EndPos()) {
+		return items
+	}
+
+	if doc.innermostBracket(offset) == regexl.TokenType_OpenCurlyBracket {
+
+		for _, builtin := range regexl.BuiltinOptions {
+			items = append(items, completionItem{
+				Label:         builtin.Name,
+				Kind:          completionKindProperty,
+				Detail:        builtin.Signature,
+				Documentation: &markupContent{Kind: "markdown", Value: builtin.Doc},
+				InsertText:    builtin.Name + ": ",
+			})
+		}
+
+		return items
+	}
+
+	hasSelect := false
+	for _, t := range doc.Tokens {
+
+		if t.Type == regexl.TokenType_Keyword && t.Val == "select" {
+			hasSelect = true
+		}
+	}
+
+	if !hasSelect {
+		items = append(items, completionItem{
+			Label: "select",
+			Kind:  completionKindKeyword,
+		})
+	}
+
+	for _, builtin := range regexl.BuiltinFuncs {
+		items = append(items, completionItem{
+			Label:         builtin.Name,
+			Kind:          completionKindFunction,
+			Detail:        builtin.Signature,
+			Documentation: &markupContent{Kind: "markdown", Value: builtin.Doc},
+		})
+	}
+
+	return items
+}
+
+// definition goes from the name passed to same_as_capture to the capture_as defining it, as capture names are the only user defined names in Regexl
+func (s *server) definition(doc *document, offset int) *location {
+
+	for _, ref := range doc.findFuncs("same_as_capture") {
+
+		nameLit, ok := stringArg(ref, 0)
+		if !ok || offset < int(nameLit.StartPos()) || offset > int(nameLit.EndPos()) {
+			continue
+		}
+
+		for _, capture := range doc.findFuncs("capture_as") {
+
+			if captureNameLit, ok := stringArg(capture, 0); ok && captureNameLit.Value == nameLit.Value {
+				return &location{
+					URI:   doc.URI,
+					Range: doc.rangeOf(int(captureNameLit.StartPos()), int(captureNameLit.EndPos())),
+				}
+			}
+		}
+	}
+
+	return nil
+}
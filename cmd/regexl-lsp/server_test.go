@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDocumentPositions(t *testing.T) {
+
+	// '😀' is 4 bytes in UTF-8 and 2 code units in UTF-16, while 'é' is 2 bytes and 1 code unit
+	doc := newDocument("file:///test.regexl", "select '😀é'\n+ 'b'")
+
+	testCases := []struct {
+		desc   string
+		offset int
+		pos    position
+	}{
+		{
+			desc:   "Start",
+			offset: 0,
+			pos:    position{Line: 0, Character: 0},
+		},
+		{
+			desc:   "Before non-BMP character",
+			offset: 8,
+			pos:    position{Line: 0, Character: 8},
+		},
+		{
+			desc:   "After non-BMP character",
+			offset: 12,
+			pos:    position{Line: 0, Character: 10},
+		},
+		{
+			desc:   "After two byte character",
+			offset: 14,
+			pos:    position{Line: 0, Character: 11},
+		},
+		{
+			desc:   "Second line",
+			offset: 19,
+			pos:    position{Line: 1, Character: 3},
+		},
+		{
+			desc:   "End",
+			offset: len(doc.Text),
+			pos:    position{Line: 1, Character: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			if pos := doc.position(tc.offset); pos != tc.pos {
+				t.Errorf("Position of offset is wrong. Offset=%d; Expected=%+v; Got=%+v\n", tc.offset, tc.pos, pos)
+			}
+
+			if offset := doc.offset(tc.pos); offset != tc.offset {
+				t.Errorf("Offset of position is wrong. Position=%+v; Expected=%d; Got=%d\n", tc.pos, tc.offset, offset)
+			}
+		})
+	}
+
+	// Positions past the end of a line or the document are clamped
+	clampCases := []struct {
+		pos    position
+		offset int
+	}{
+		{pos: position{Line: 0, Character: 100}, offset: strings.Index(doc.Text, "\n")},
+		{pos: position{Line: 5, Character: 0}, offset: len(doc.Text)},
+		{pos: position{Line: -1, Character: 0}, offset: 0},
+	}
+
+	for _, cc := range clampCases {
+
+		if offset := doc.offset(cc.pos); offset != cc.offset {
+			t.Errorf("Offset of out of range position is wrong. Position=%+v; Expected=%d; Got=%d\n", cc.pos, cc.offset, offset)
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{"text":"é😀"}}`
+	in := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n%s", len(body), body)
+	in += "Content-Length: 2\r\n\r\n{}"
+	in += "Content-Length: 5\r\n\r\n{oops"
+
+	out := &bytes.Buffer{}
+	c := newConn(strings.NewReader(in), out)
+
+	msg, err := c.Read()
+	if err != nil {
+		t.Fatalf("Reading message failed. Err=%v\n", err)
+	}
+
+	if msg.Method != "textDocument/hover" || string(msg.Id) != "1" || msg.IsNotification() || !strings.Contains(string(msg.Params), "é😀") {
+		t.Fatalf("Read the wrong message. Message=%+v\n", msg)
+	}
+
+	msg, err = c.Read()
+	if err != nil || !msg.IsNotification() {
+		t.Fatalf("Message without id must be a notification. Message=%+v; Err=%v\n", msg, err)
+	}
+
+	_, err = c.Read()
+	if respErr, ok := err.(*responseError); !ok || respErr.Code != codeParseError {
+		t.Fatalf("Reading invalid JSON must return a parse error. Err=%v\n", err)
+	}
+
+	_, err = c.Read()
+	if err != io.EOF {
+		t.Fatalf("Reading after the last message must return io.EOF. Err=%v\n", err)
+	}
+
+	// Replies
+	err = c.Reply(json.RawMessage("1"), map[string]string{"text": "é"}, nil)
+	if err != nil {
+		t.Fatalf("Replying failed. Err=%v\n", err)
+	}
+
+	err = c.Reply(json.RawMessage("2"), nil, nil)
+	if err != nil {
+		t.Fatalf("Replying with a null result failed. Err=%v\n", err)
+	}
+
+	err = c.Reply(json.RawMessage("3"), nil, &responseError{Code: codeMethodNotFound, Message: "nope"})
+	if err != nil {
+		t.Fatalf("Replying with an error failed. Err=%v\n", err)
+	}
+
+	expectedBodies := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"text":"é"}}`,
+		`{"jsonrpc":"2.0","id":2,"result":null}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"nope"}}`,
+	}
+
+	expectedOut := ""
+	for _, b := range expectedBodies {
+		expectedOut += fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(b), b)
+	}
+
+	if out.String() != expectedOut {
+		t.Fatalf("Written replies are wrong. Expected=%q; Got=%q\n", expectedOut, out.String())
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+
+	const uri = "file:///test.regexl"
+	text := "set_options({case_sensitive: true})\n\nselect capture_as('year', 'y') + from_to('a', 'z') + same_as_capture('year')"
+
+	out := &bytes.Buffer{}
+	s := newServer(newConn(strings.NewReader(""), out), "go")
+
+	handle := func(method string, params any) any {
+
+		paramsBytes, err := json.Marshal(params)
+		if err != nil {
+			t.Fatalf("Marshaling params failed. Err=%v\n", err)
+		}
+
+		result, err := s.Handle(&message{Jsonrpc: "2.0", Id: json.RawMessage("1"), Method: method, Params: paramsBytes})
+		if err != nil {
+			t.Fatalf("Handling '%s' failed. Err=%v\n", method, err)
+		}
+
+		return result
+	}
+
+	positionParams := func(line, character int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     position{Line: line, Character: character},
+		}
+	}
+
+	handle("initialize", map[string]any{})
+	handle("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": text}})
+
+	// from_to outside any_chars_of is reported by the checker
+	if !strings.Contains(out.String(), "textDocument/publishDiagnostics") || !strings.Contains(out.String(), "from_to") {
+		t.Fatalf("Opening a document with errors must publish its diagnostics. Output=%s\n", out.String())
+	}
+
+	t.Run("Hover", func(t *testing.T) {
+
+		h, _ := handle("textDocument/hover", positionParams(2, 8)).(*hover)
+		if h == nil || !strings.Contains(h.Contents.Value, "capture_as('name', x)") || !strings.Contains(h.Contents.Value, "(?P<year>y)") {
+			t.Fatalf("Hover of capture_as must have its docs and regex. Hover=%+v\n", h)
+		}
+
+		// The checker rejects from_to outside any_chars_of, so no regex is shown
+		h, _ = handle("textDocument/hover", positionParams(2, 34)).(*hover)
+		if h == nil || !strings.Contains(h.Contents.Value, "from_to(from, to)") || strings.Contains(h.Contents.Value, "Compiles to") {
+			t.Fatalf("Hover of from_to must have its docs but no regex. Hover=%+v\n", h)
+		}
+
+		h, _ = handle("textDocument/hover", positionParams(0, 14)).(*hover)
+		if h == nil || !strings.Contains(h.Contents.Value, "case_sensitive: bool") {
+			t.Fatalf("Hover of an option must have its docs. Hover=%+v\n", h)
+		}
+
+		if h, _ := handle("textDocument/hover", positionParams(1, 0)).(*hover); h != nil {
+			t.Fatalf("Hover on an empty line must be empty. Hover=%+v\n", h)
+		}
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+
+		items, _ := handle("textDocument/completion", positionParams(0, 13)).([]completionItem)
+		if len(items) == 0 || items[0].Kind != completionKindProperty || items[0].Label != "case_sensitive" {
+			t.Fatalf("Completion inside an object must list the options. Items=%+v\n", items)
+		}
+
+		items, _ = handle("textDocument/completion", positionParams(2, 7)).([]completionItem)
+		hasFunc := false
+		for _, item := range items {
+
+			if item.Kind == completionKindKeyword {
+				t.Fatalf("Completion must not offer 'select' when the query already has one. Items=%+v\n", items)
+			}
+
+			hasFunc = hasFunc || item.Label == "any_chars_of"
+		}
+
+		if !hasFunc {
+			t.Fatalf("Completion must list the built-in functions. Items=%+v\n", items)
+		}
+
+		// Inside a string
+		items, _ = handle("textDocument/completion", positionParams(2, 20)).([]completionItem)
+		if len(items) != 0 {
+			t.Fatalf("Completion inside a string must be empty. Items=%+v\n", items)
+		}
+	})
+
+	t.Run("Definition", func(t *testing.T) {
+
+		loc, _ := handle("textDocument/definition", positionParams(2, 71)).(*location)
+		expectedRange := lspRange{Start: position{Line: 2, Character: 18}, End: position{Line: 2, Character: 24}}
+		if loc == nil || loc.URI != uri || loc.Range != expectedRange {
+			t.Fatalf("Definition of the capture name is wrong. Expected=%+v; Got=%+v\n", expectedRange, loc)
+		}
+
+		if loc, _ := handle("textDocument/definition", positionParams(2, 2)).(*location); loc != nil {
+			t.Fatalf("Definition outside of same_as_capture must be empty. Location=%+v\n", loc)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+
+		_, err := s.Handle(&message{Jsonrpc: "2.0", Id: json.RawMessage("1"), Method: "textDocument/hover", Params: json.RawMessage(`{"textDocument":{"uri":"file:///closed.regexl"}}`)})
+		if respErr, ok := err.(*responseError); !ok || respErr.Code != codeInvalidParams {
+			t.Fatalf("Hover of a document that isn't open must fail with invalid params. Err=%v\n", err)
+		}
+
+		_, err = s.Handle(&message{Jsonrpc: "2.0", Id: json.RawMessage("1"), Method: "unknown/method"})
+		if respErr, ok := err.(*responseError); !ok || respErr.Code != codeMethodNotFound {
+			t.Fatalf("Unknown requests must fail with method not found. Err=%v\n", err)
+		}
+
+		if _, err = s.Handle(&message{Jsonrpc: "2.0", Method: "$/cancelRequest"}); err != nil {
+			t.Fatalf("Unknown notifications must be ignored. Err=%v\n", err)
+		}
+	})
+}
+
+func TestServerRun(t *testing.T) {
+
+	frame := func(msgs ...string) string {
+
+		s := ""
+		for _, msg := range msgs {
+			s += fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(msg), msg)
+		}
+
+		return s
+	}
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"dialect":"javascript"}}}`
+	didOpen := `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.regexl","text":"select starts_with("}}}`
+	shutdown := `{"jsonrpc":"2.0","id":2,"method":"shutdown"}`
+	exit := `{"jsonrpc":"2.0","method":"exit"}`
+
+	out := &bytes.Buffer{}
+	s := newServer(newConn(strings.NewReader(frame(initialize, didOpen, shutdown, exit)), out), "go")
+	err := s.Run()
+	if err != nil {
+		t.Fatalf("Running the server failed. Err=%v\n", err)
+	}
+
+	if s.dialect != "javascript" {
+		t.Fatalf("Initialization options must set the dialect. Dialect=%s\n", s.dialect)
+	}
+
+	// Replies to initialize and shutdown, and the diagnostics notification of didOpen
+	replies := out.String()
+	if strings.Count(replies, "Content-Length:") != 3 || !strings.Contains(replies, `"serverInfo":{"name":"regexl-lsp"}`) ||
+		!strings.Contains(replies, `"severity":1`) || !strings.Contains(replies, `{"jsonrpc":"2.0","id":2,"result":null}`) {
+		t.Fatalf("Server wrote the wrong messages. Output=%s\n", replies)
+	}
+
+	// Exit without shutdown is an error
+	s = newServer(newConn(strings.NewReader(frame(exit)), io.Discard), "go")
+	if err := s.Run(); err == nil {
+		t.Fatalf("Exiting without shutdown must return an error\n")
+	}
+
+	// An unknown dialect is rejected when initializing
+	badInitialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"dialect":"nope"}}}`
+	out.Reset()
+	s = newServer(newConn(strings.NewReader(frame(badInitialize)), out), "go")
+	if err := s.Run(); err != nil || !strings.Contains(out.String(), fmt.Sprintf(`"code":%d`, codeInvalidParams)) {
+		t.Fatalf("Initializing with an unknown dialect must reply with invalid params. Output=%s; Err=%v\n", out.String(), err)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bloeys/regexl"
+)
+
+const usage = `regexl compiles Regexl queries and runs them against text.
+
+Usage:
+	regexl <command> [flags] [files...]
+
+Commands:
+	compile   Print the regex produced by the query for a regex dialect (-dialect, default 'go')
+	match     Print the text matched by the query in each line of the files or stdin.
+	          Only the first match of each line is printed unless the query sets 'find_all_matches: true'
+	grep      Print the lines of the files or stdin that match the query
+	tokens    Print the tokens of the query as JSON
+	ast       Print the AST of the query as a tree, or as JSON with -json
+	fmt       Format .regexl files, or stdin, in the canonical style. Like gofmt, -w writes the result to the files
+	          and -l lists the files whose formatting differs
+	dialects  Print the regex dialects that can be passed to -dialect
+
+Except for fmt, the query is passed with -e (e.g. -e "select 'hello'") or read from a file with -f.
+For compile, tokens and ast the query is read from stdin if neither is passed.
+
+Run 'regexl <command> -h' for the flags of a command.
+`
+
+// errNoMatch is returned by the match and grep commands when nothing matched, which exits with status 1 like grep does
+var errNoMatch = errors.New("no match")
+
+func main() {
+
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "compile":
+		err = runCompile(args)
+	case "match":
+		err = runMatch(cmd, args, false)
+	case "grep":
+		err = runMatch(cmd, args, true)
+	case "tokens":
+		err = runTokens(args)
+	case "ast":
+		err = runAst(args)
+	case "fmt":
+		err = runFmt(args)
+	case "dialects":
+		fmt.Println(strings.Join(regexl.Dialects(), "\n"))
+	default:
+		err = fmt.Errorf("unknown command '%s'. Run 'regexl help' for the list of commands", cmd)
+	}
+
+	if errors.Is(err, errNoMatch) {
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "regexl %s: %s\n", cmd, err)
+		os.Exit(2)
+	}
+}
+
+// queryFlags adds the flags used to pass a query, and returns a function that reads the query after the flags are parsed
+func queryFlags(fs *flag.FlagSet, readStdin bool) func() (string, error) {
+
+	queryText := fs.String("e", "", "the Regexl query")
+	queryFile := fs.String("f", "", "path of a file holding the Regexl query (e.g. query.regexl)")
+
+	return func() (string, error) {
+
+		switch {
+
+		case *queryText != "" && *queryFile != "":
+			return "", fmt.Errorf("only one of -e and -f can be used")
+
+		case *queryText != "":
+			return *queryText, nil
+
+		case *queryFile != "":
+			b, err := os.ReadFile(*queryFile)
+			return string(b), err
+
+		case readStdin:
+			b, err := io.ReadAll(os.Stdin)
+			return string(b), err
+
+		default:
+			return "", fmt.Errorf("a query must be passed with -e or -f")
+		}
+	}
+}
+
+// queryError shows the line of the query that caused the error, so that the error is easy to find in multi-line queries
+func queryError(query string, err error) error {
+	return errors.New(regexl.RenderError(query, err))
+}
+
+func runCompile(args []string) error {
+
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	dialect := fs.String("dialect", regexl.Dialect_Go, "the regex dialect to compile to. One of: "+strings.Join(regexl.Dialects(), ", "))
+	readQuery := queryFlags(fs, true)
+	fs.Parse(args)
+
+	query, err := readQuery()
+	if err != nil {
+		return err
+	}
+
+	rl := regexl.NewRegexl(query)
+	err = rl.CompileFor(*dialect)
+	if err != nil {
+		return queryError(query, err)
+	}
+
+	for _, diag := range rl.Diagnostics {
+		fmt.Fprintf(os.Stderr, "note: %s: %s\n", regexl.Locate(query, diag.Pos), diag.Msg)
+	}
+
+	fmt.Println(rl.CompiledString)
+	return nil
+}
+
+// runMatch runs the query against each line of the passed files or stdin. If printLines is true whole matching lines are printed,
+// otherwise the matched text is printed
+func runMatch(name string, args []string, printLines bool) error {
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	readQuery := queryFlags(fs, false)
+	fs.Parse(args)
+
+	query, err := readQuery()
+	if err != nil {
+		return err
+	}
+
+	// Compiling runs the checker as well, so the query is rejected in the same way as with 'regexl compile'
+	rl := regexl.NewRegexl(query)
+	err = rl.Compile()
+	if err != nil {
+		return queryError(query, err)
+	}
+
+	m := &matcher{
+		Re:             rl.CompiledRegexp,
+		PrintLines:     printLines,
+		FindAllMatches: rl.Opts.FindAllMatches,
+		PrintFileNames: fs.NArg() > 1,
+		Out:            bufio.NewWriter(os.Stdout),
+	}
+	defer m.Out.Flush()
+
+	if fs.NArg() == 0 {
+		err = m.matchReader("(stdin)", os.Stdin)
+	}
+
+	for _, path := range fs.Args() {
+
+		err = m.matchFile(path)
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !m.HasMatch {
+		return errNoMatch
+	}
+
+	return nil
+}
+
+type matcher struct {
+	Re             *regexp.Regexp
+	PrintLines     bool
+	FindAllMatches bool
+	PrintFileNames bool
+	Out            *bufio.Writer
+
+	HasMatch bool
+}
+
+func (m *matcher) matchFile(path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return m.matchReader(path, f)
+}
+
+func (m *matcher) matchReader(name string, r io.Reader) error {
+
+	prefix := ""
+	if m.PrintFileNames {
+		prefix = name + ":"
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if m.PrintLines {
+
+			if m.Re.MatchString(line) {
+				m.HasMatch = true
+				fmt.Fprintln(m.Out, prefix+line)
+			}
+
+			continue
+		}
+
+		maxMatches := 1
+		if m.FindAllMatches {
+			maxMatches = -1
+		}
+
+		for _, match := range m.Re.FindAllString(line, maxMatches) {
+			m.HasMatch = true
+			fmt.Fprintln(m.Out, prefix+match)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s failed. Err=%s", name, err)
+	}
+
+	return nil
+}
+
+func runTokens(args []string) error {
+
+	fs := flag.NewFlagSet("tokens", flag.ExitOnError)
+	readQuery := queryFlags(fs, true)
+	fs.Parse(args)
+
+	query, err := readQuery()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := regexl.NewParser(query).Tokenize()
+	if err != nil {
+		return queryError(query, err)
+	}
+
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(b))
+	return nil
+}
+
+func runAst(args []string) error {
+
+	fs := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJson := fs.Bool("json", false, "print the AST nodes as JSON instead of a tree")
+	readQuery := queryFlags(fs, true)
+	fs.Parse(args)
+
+	query, err := readQuery()
+	if err != nil {
+		return err
+	}
+
+	ast, err := regexl.NewRegexl(query).Parse()
+	if err != nil {
+		return queryError(query, err)
+	}
+
+	if !*asJson {
+		ast.FprintTree(os.Stdout)
+		return nil
+	}
+
+	b, err := json.MarshalIndent(ast.Nodes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(b))
+	return nil
+}
+
+func runFmt(args []string) error {
+
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the files instead of printing it")
+	list := fs.Bool("l", false, "print the paths of the files whose formatting differs from the canonical style")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+
+		if *write || *list {
+			return fmt.Errorf("-w and -l need files to be passed")
+		}
+
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		formatted, err := regexl.Format(string(b))
+		if err != nil {
+			return queryError(string(b), err)
+		}
+
+		fmt.Print(formatted)
+		return nil
+	}
+
+	for _, path := range fs.Args() {
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		formatted, err := regexl.Format(string(b))
+		if err != nil {
+			return fmt.Errorf("%s:%s", path, regexl.RenderError(string(b), err))
+		}
+
+		if *list && formatted != string(b) {
+			fmt.Println(path)
+		}
+
+		if *write {
+
+			if formatted == string(b) {
+				continue
+			}
+
+			err = os.WriteFile(path, []byte(formatted), 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !*write && !*list {
+			fmt.Print(formatted)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package regexl
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrorList is a list of errors found in a query, so that one compile can report all the problems of a query at once like go/scanner.ErrorList does.
+// Errors in the list are usually SpanErrors
+type ErrorList []error
+
+var _ error = ErrorList{}
+
+// Add appends the error to the list. Nil errors are ignored, and the errors of an ErrorList are appended one by one
+func (el *ErrorList) Add(err error) {
+
+	if err == nil {
+		return
+	}
+
+	if otherList, ok := err.(ErrorList); ok {
+		*el = append(*el, otherList...)
+		return
+	}
+
+	*el = append(*el, err)
+}
+
+// Sort sorts the errors by where they start in the query, where errors without a span come first.
+// Errors starting at the same position keep their order
+func (el ErrorList) Sort() {
+
+	slices.SortStableFunc(el, func(a, b error) int {
+		return int(errorStart(a) - errorStart(b))
+	})
+}
+
+// RemoveMultiples sorts the list and removes the same problem being found by more than one step (e.g. an unclosed bracket is found by both
+// Parser.ValidateTokens and Ast.Gen, and a bad arity by both Checker.Check and the backend).
+//
+// At each position all the errors of the step that found the first error there are kept, as one step doesn't report the same problem twice,
+// while the errors of the other steps are removed. Errors repeating the message and span of a kept error are removed as well
+func (el *ErrorList) RemoveMultiples() {
+
+	el.Sort()
+
+	uniqueErrs := (*el)[:0]
+	// firstAtStart is the index in uniqueErrs of the first error kept at the position being checked
+	firstAtStart := -1
+	for _, err := range *el {
+
+		start := errorStart(err)
+		if start < 0 {
+			uniqueErrs = append(uniqueErrs, err)
+			continue
+		}
+
+		if firstAtStart == -1 || errorStart(uniqueErrs[firstAtStart]) != start {
+			firstAtStart = len(uniqueErrs)
+			uniqueErrs = append(uniqueErrs, err)
+			continue
+		}
+
+		isRepeat := slices.ContainsFunc(uniqueErrs[firstAtStart:], func(kept error) bool { return isSameError(kept, err) })
+		if errorStep(err) != errorStep(uniqueErrs[firstAtStart]) || isRepeat {
+			continue
+		}
+
+		uniqueErrs = append(uniqueErrs, err)
+	}
+
+	clear((*el)[len(uniqueErrs):])
+	*el = uniqueErrs
+}
+
+// errorStep returns the type of the SpanError in err, which tells the step that found it (e.g. *CheckError for Checker.Check)
+func errorStep(err error) reflect.Type {
+
+	var spanErr SpanError
+	errors.As(err, &spanErr)
+	return reflect.TypeOf(spanErr)
+}
+
+// isSameError returns true if both errors have the same span and message
+func isSameError(a, b error) bool {
+
+	var aSpanErr, bSpanErr SpanError
+	if !errors.As(a, &aSpanErr) || !errors.As(b, &bSpanErr) {
+		return a.Error() == b.Error()
+	}
+
+	aStart, aEnd := aSpanErr.Span()
+	bStart, bEnd := bSpanErr.Span()
+	return aStart == bStart && aEnd == bEnd && aSpanErr.Unwrap().Error() == bSpanErr.Unwrap().Error()
+}
+
+func (el ErrorList) Error() string {
+
+	switch len(el) {
+	case 0:
+		return "no errors"
+	case 1:
+		return el[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", el[0], len(el)-1)
+	}
+}
+
+// Err returns nil if the list is empty, otherwise it returns the list
+func (el ErrorList) Err() error {
+
+	if len(el) == 0 {
+		return nil
+	}
+
+	return el
+}
+
+// Unwrap returns the errors in the list, so that errors.As and errors.Is check each of them
+func (el ErrorList) Unwrap() []error {
+	return el
+}
+
+// Errors returns the errors in err, which are the errors of the list if err is an ErrorList, otherwise err itself.
+// Nil is returned if err is nil
+func Errors(err error) []error {
+
+	if err == nil {
+		return nil
+	}
+
+	var el ErrorList
+	if errors.As(err, &el) {
+		return el
+	}
+
+	return []error{err}
+}
+
+// errorStart returns the position where the error starts, or -1 if the error isn't a SpanError
+func errorStart(err error) TokenPos {
+
+	var spanErr SpanError
+	if !errors.As(err, &spanErr) {
+		return -1
+	}
+
+	start, _ := spanErr.Span()
+	return start
+}
+
+// renderErrorList renders each error of the list with RenderError
+func renderErrorList(query string, el ErrorList) string {
+
+	rendered := make([]string, 0, len(el))
+	for _, err := range el {
+		rendered = append(rendered, RenderError(query, err))
+	}
+
+	return strings.Join(rendered, "\n")
+}
@@ -5,6 +5,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -27,6 +28,23 @@ var _ error = &ParserError{}
 type ParserError struct {
 	Err error
 	Pos TokenPos
+	// End is the position after the last byte of what caused the error. If End isn't after Pos the error is about the character at Pos
+	End TokenPos
+
+	// query is set by setErrorsQuery
+	query string
+}
+
+func (te *ParserError) Span() (start, end TokenPos) {
+	return errorSpan(te.Pos, te.End)
+}
+
+func (te *ParserError) Unwrap() error {
+	return te.Err
+}
+
+func (te *ParserError) setQuery(query string) {
+	te.query = query
 }
 
 func (te *ParserError) Error() string {
@@ -35,7 +53,7 @@ func (te *ParserError) Error() string {
 		return ""
 	}
 
-	return fmt.Sprintf("parser error: loc=%d; err=%s", te.Pos, te.Err.Error())
+	return fmt.Sprintf("parser error: loc=%s; err=%s", errorLoc(te.query, te.Pos), te.Err.Error())
 }
 
 func (p *Parser) Tokenize() (tokens []Token, err error) {
@@ -67,7 +85,12 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 	*/
 	addToken := func(t *Token) (latestToken *Token) {
 
-		if t.Type != TokenType_String {
+		// Leading spaces of comments are kept, as they might be used to align text over multiple comments
+		switch t.Type {
+		case TokenType_String:
+		case TokenType_Comment:
+			t.Val = strings.TrimRightFunc(t.Val, unicode.IsSpace)
+		default:
 			t.Val = strings.TrimSpace(t.Val)
 		}
 
@@ -103,12 +126,55 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 		}
 	}
 
+	// endToken adds the token that ended at a space or at the end of the query, which might be a literal or keyword
+	endToken := func(t *Token) {
+
+		prevToken := addToken(t)
+		tryAssignTypeToPossibleLiteralToken(prevToken)
+
+		// Handle keywords and use is empty to protect against nil
+		if !prevToken.IsEmpty() && prevToken.Type == TokenType_Unknown {
+
+			if slices.Contains(keywords, prevToken.Val) {
+				prevToken.Type = TokenType_Keyword
+			} else if prevToken.Val == "or" {
+				// '|' is its own token, while 'or' is a word ended by a space like keywords
+				prevToken.Type = TokenType_Or
+			}
+		}
+	}
+
+	// line and column are of the current rune, and are kept while scanning so tokens don't have to count the lines before them
+	line := 1
+	column := 0
+	startToken := func(t *Token, pos int) {
+		t.Pos = TokenPos(pos)
+		t.Line = line
+		t.Column = column
+	}
+
+	errs := ErrorList{}
 	inString := false
 	inComment := false
+	// stringQuote is the quote that started the current string, which is ` for raw strings
+	stringQuote := '\''
+	// skipUntil is the position after an escape sequence, whose characters are skipped as they were already handled
+	skipUntil := 0
 	token := &Token{}
 	token.MakeEmpty()
 	for runeStartByteIndex, c := range p.Query {
 
+		if runeStartByteIndex > 0 && p.Query[runeStartByteIndex-1] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+
+		if runeStartByteIndex < skipUntil {
+			continue
+		}
+
 		if inComment {
 
 			if c != '\n' {
@@ -124,42 +190,31 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 		if inString {
 
-			if c != '\'' && c != '\\' {
-				token.Val += string(c)
+			if c == stringQuote {
+				token.Raw = p.Query[token.Pos : runeStartByteIndex+1]
+				addToken(token)
+				inString = false
 				continue
 			}
 
-			if c == '\'' {
-
-				addToken(token)
-
-				// token.Val = "'"
-				// token.Type = TokenType_Single_Quote
-				// token.Loc = runeStartByteIndex
-				// addToken(token)
-
-				inString = false
+			// Raw strings take their content as is, so a backslash is only special in normal strings
+			if c != '\\' || stringQuote == '`' {
+				token.Val += string(c)
 				continue
 			}
 
-			// Handle backslash in string as it might escape the end string character
-			nextRune, err := p.GetNextRuneByByteIndex(runeStartByteIndex)
+			// Unknown escapes are reported and dropped from the value, and the string continues after them
+			escapedVal, escapeLen, err := p.readEscape(runeStartByteIndex)
 			if err != nil {
-				return tokens, &ParserError{
+				errs.Add(&ParserError{
 					Err: err,
 					Pos: TokenPos(runeStartByteIndex),
-				}
+					End: TokenPos(runeStartByteIndex + escapeLen),
+				})
 			}
 
-			if nextRune == '\'' {
-				token.Val += string(c)
-				inString = false
-				addToken(token)
-				continue
-			}
-
-			// Its just a normal backslash not escaping anything so we let it be
-			token.Val += string(c)
+			token.Val += escapedVal
+			skipUntil = runeStartByteIndex + escapeLen
 			continue
 		}
 
@@ -171,17 +226,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 		case '\t':
 			fallthrough
 		case ' ':
-
-			prevToken := addToken(token)
-			tryAssignTypeToPossibleLiteralToken(prevToken)
-
-			// Handle keywords and use is empty to protect against nil
-			if !prevToken.IsEmpty() && prevToken.Type == TokenType_Unknown {
-
-				if slices.Contains(keywords, prevToken.Val) {
-					prevToken.Type = TokenType_Keyword
-				}
-			}
+			endToken(token)
 
 		case ':':
 
@@ -192,7 +237,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = ":"
 			token.Type = TokenType_Colon
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case '+':
@@ -200,7 +245,15 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = "+"
 			token.Type = TokenType_Plus
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
+			addToken(token)
+
+		case '|':
+			addToken(token)
+
+			token.Val = "|"
+			token.Type = TokenType_Or
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case ',':
@@ -210,7 +263,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = ","
 			token.Type = TokenType_Comma
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case '(':
@@ -221,7 +274,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = "("
 			token.Type = TokenType_OpenBracket
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case ')':
@@ -230,7 +283,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = ")"
 			token.Type = TokenType_CloseBracket
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case '{':
@@ -238,7 +291,7 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = "{"
 			token.Type = TokenType_OpenCurlyBracket
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
 		case '}':
@@ -247,53 +300,71 @@ func (p *Parser) Tokenize() (tokens []Token, err error) {
 
 			token.Val = "}"
 			token.Type = TokenType_CloseCurlyBracket
-			token.Pos = TokenPos(runeStartByteIndex)
-			addToken(token)
-
-		case '\'':
+			startToken(token, runeStartByteIndex)
 			addToken(token)
 
-			// token.Val = "'"
-			// token.Type = TokenType_Single_Quote
-			// token.Loc = runeStartByteIndex
-			// addToken(token)
+		case '\'', '`':
+			// endToken so that a word right before the quote (e.g. 'or' of "'a' or'b'") is still typed
+			endToken(token)
 
 			inString = true
+			stringQuote = c
 			token.Type = TokenType_String
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 
 		case '/':
 
-			nextRune, err := p.GetNextRuneByByteIndex(runeStartByteIndex)
-			if err != nil {
-				return tokens, &ParserError{
-					Err: err,
-					Pos: TokenPos(runeStartByteIndex),
-				}
-			}
-
+			// A '/' at the end of the query has no next rune, which is reported the same as any other '/' not starting a comment
+			nextRune, _ := p.GetNextRuneByByteIndex(runeStartByteIndex)
 			if nextRune != '/' {
-				return tokens, &ParserError{
+				errs.Add(&ParserError{
 					Err: fmt.Errorf("found '/' in an unexpected location. '/' can only be used for comments or in strings"),
 					Pos: TokenPos(runeStartByteIndex),
-				}
+				})
+				continue
 			}
 
 			addToken(token)
 			token.Type = TokenType_Comment
-			token.Pos = TokenPos(runeStartByteIndex)
+			startToken(token, runeStartByteIndex)
 			inComment = true
 
 		default:
 			token.Val += string(c)
 			if !token.HasLoc() {
-				token.Pos = TokenPos(runeStartByteIndex)
+				startToken(token, runeStartByteIndex)
 			}
 		}
 	}
 
-	err = p.ValidateTokens(tokens)
-	return tokens, err
+	// The last token doesn't end with a space or new line
+	switch {
+	case inComment:
+		token.Val = token.Val[1:]
+		addToken(token)
+	case inString:
+
+		closingQuote := "quote"
+		if stringQuote == '`' {
+			closingQuote = "backtick"
+		}
+
+		errs.Add(&ParserError{
+			Err: fmt.Errorf("invalid regexl query: string doesn't have a closing %s", closingQuote),
+			Pos: token.Pos,
+			End: TokenPos(len(p.Query)),
+		})
+
+		// The string is still added so that the rest of the query is checked as if it was closed
+		token.Raw = p.Query[token.Pos:]
+		addToken(token)
+	default:
+		endToken(token)
+	}
+
+	errs.Add(p.ValidateTokens(tokens))
+	setErrorsQuery(errs, p.Query)
+	return tokens, errs.Err()
 }
 
 func (p *Parser) ValidateTokens(tokens []Token) error {
@@ -347,6 +418,7 @@ func (p *Parser) ValidateTokens(tokens []Token) error {
 		curr.Next = nil
 	}
 
+	errs := ErrorList{}
 	selectCounter := 0
 	for i := 0; i < len(tokens); i++ {
 
@@ -360,18 +432,20 @@ func (p *Parser) ValidateTokens(tokens []Token) error {
 
 				selectCounter++
 				if selectCounter > 1 {
-					return &ParserError{
-						Err: fmt.Errorf("invalid regexl query: found multiple 'select' keywords while only one is allowed; token=%+v; query=%s", t, p.Query),
+					errs.Add(&ParserError{
+						Err: fmt.Errorf("invalid regexl query: found multiple 'select' keywords while only one is allowed"),
 						Pos: t.Pos,
-					}
+						End: t.EndPos(),
+					})
 				}
 			}
 
 		case TokenType_Unknown:
-			return &ParserError{
-				Err: fmt.Errorf("invalid regexl query: found token with type=unknown after tokenization; token=%+v; query=%s", t, p.Query),
+			errs.Add(&ParserError{
+				Err: fmt.Errorf("invalid regexl query: '%s' is not a keyword, function call or literal (e.g. 'abc', 10 or true)", t.Val),
 				Pos: t.Pos,
-			}
+				End: t.EndPos(),
+			})
 
 		case TokenType_OpenBracket:
 			fallthrough
@@ -389,36 +463,50 @@ func (p *Parser) ValidateTokens(tokens []Token) error {
 				continue
 			}
 
+			// The bracket is reported and then ignored, so brackets after it are still matched correctly
+			bracketsCounter = 0
+
 			if t.Type == TokenType_CloseCurlyBracket {
-				return &ParserError{
-					Err: fmt.Errorf("invalid regexl query: found a closed curly bracket without an opening curly bracket; token=%+v; query=%s", t, p.Query),
+				errs.Add(&ParserError{
+					Err: fmt.Errorf("invalid regexl query: found a closed curly bracket without an opening curly bracket"),
 					Pos: t.Pos,
-				}
+				})
+				continue
 			}
 
-			return &ParserError{
-				Err: fmt.Errorf("invalid regexl query: found a closed bracket without an opening bracket; token=%+v; query=%s", t, p.Query),
+			errs.Add(&ParserError{
+				Err: fmt.Errorf("invalid regexl query: found a closed bracket without an opening bracket"),
 				Pos: t.Pos,
-			}
+			})
 		}
 	}
 
 	// Negative case is handled inside the switch case, so this is for brackets that opened but didn't close
-	// We only show the first unclosed bracket, but we can use the tree to show all of them
-	if bracketsCounter != 0 {
-		return &ParserError{
-			Err: fmt.Errorf("invalid regexl query: found an opening bracket without a closing bracket pair; first unclosed bracket token=%+v; query=%s", openBracketsList.Bracket, p.Query),
-			Pos: openBracketsList.Bracket.Pos,
-		}
+	for b := openBracketsList; bracketsCounter > 0 && b != nil && b.Bracket != nil; b = b.Next {
+		errs.Add(&ParserError{
+			Err: fmt.Errorf("invalid regexl query: found an opening bracket without a closing bracket pair"),
+			Pos: b.Bracket.Pos,
+		})
 	}
 
+	// The missing select is put on the first token, which is where it is expected to be
 	if selectCounter == 0 {
-		return &ParserError{
-			Err: fmt.Errorf("invalid regexl query: 'select' keyword is required but wasn't found; query=%s", p.Query),
+
+		selectErr := &ParserError{
+			Err: fmt.Errorf("invalid regexl query: 'select' keyword is required but wasn't found"),
+			Pos: TokenPos(len(p.Query)),
+		}
+
+		if len(tokens) > 0 {
+			selectErr.Pos = tokens[0].Pos
+			selectErr.End = tokens[0].EndPos()
 		}
+
+		errs.Add(selectErr)
 	}
 
-	return nil
+	setErrorsQuery(errs, p.Query)
+	return errs.Err()
 }
 
 func (p *Parser) GetRuneByByteIndex(index int) (rune, error) {
@@ -431,6 +519,90 @@ func (p *Parser) GetRuneByByteIndex(index int) (rune, error) {
 	return r, nil
 }
 
+// readEscape reads the escape sequence starting with the backslash at index, and returns the text it stands for and the length of the sequence in bytes.
+// The supported escapes are \n, \t, \r, \\, \' and \u{...} which holds a unicode code point in hex (e.g. \u{1F600}).
+// On error the returned length covers the invalid escape so that it can be skipped
+func (p *Parser) readEscape(index int) (escapedVal string, escapeLen int, err error) {
+
+	// A backslash at the end of the query escapes nothing, and the string not being closed is reported by the caller
+	rest := p.Query[index+1:]
+	if rest == "" {
+		return "", 1, nil
+	}
+
+	r, rLen := utf8.DecodeRuneInString(rest)
+	switch r {
+	case 'n':
+		return "\n", 2, nil
+	case 't':
+		return "\t", 2, nil
+	case 'r':
+		return "\r", 2, nil
+	case '\\':
+		return "\\", 2, nil
+	case '\'':
+		return "'", 2, nil
+	case 'u':
+		return readUnicodeEscape(rest)
+	default:
+		return "", 1 + rLen, fmt.Errorf("unknown escape sequence '\\%c' in string. Supported escapes are \\n, \\t, \\r, \\\\, \\' and \\u{...} (e.g. \\u{1F600})", r)
+	}
+}
+
+// readUnicodeEscape reads a '\u{...}' escape sequence, where escape is the sequence without its backslash
+func readUnicodeEscape(escape string) (escapedVal string, escapeLen int, err error) {
+
+	errNoBrackets := fmt.Errorf("escape sequence '\\u' must be followed by a hex code point in curly brackets (e.g. \\u{1F600})")
+	if !strings.HasPrefix(escape, "u{") {
+		return "", 2, errNoBrackets
+	}
+
+	hexEnd := 2
+	for hexEnd < len(escape) && strings.ContainsRune("0123456789abcdefABCDEF", rune(escape[hexEnd])) {
+		hexEnd++
+	}
+
+	if hexEnd >= len(escape) || escape[hexEnd] != '}' {
+		return "", 1 + hexEnd, errNoBrackets
+	}
+
+	hex := escape[2:hexEnd]
+	codePoint, parseErr := strconv.ParseUint(hex, 16, 32)
+	if hex == "" || len(hex) > 6 || parseErr != nil || !utf8.ValidRune(rune(codePoint)) {
+		return "", hexEnd + 2, fmt.Errorf("'\\u{%s}' is not a valid unicode code point", hex)
+	}
+
+	return string(rune(codePoint)), hexEnd + 2, nil
+}
+
+// quoteString returns s as a quoted string that Tokenize reads back as s, where quotes, backslashes and non-printable characters are escaped
+func quoteString(s string) string {
+
+	sb := strings.Builder{}
+	sb.WriteRune('\'')
+
+	for _, r := range s {
+
+		switch {
+		case r == '\'' || r == '\\':
+			sb.WriteString(`\` + string(r))
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case !unicode.IsPrint(r):
+			sb.WriteString(fmt.Sprintf(`\u{%X}`, r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	sb.WriteRune('\'')
+	return sb.String()
+}
+
 func (p *Parser) GetNextRuneByByteIndex(index int) (rune, error) {
 
 	if index >= len(p.Query) {
@@ -62,7 +62,7 @@ func regexlCompileAndMatch(this js.Value, args []js.Value) (outputString any) {
 
 	err := rl.Compile()
 	if err != nil {
-		output.ErrString = err.Error()
+		output.ErrString = regexl.RenderError(regexlQuery, err)
 		updateOutputString()
 		return
 	}
@@ -0,0 +1,234 @@
+package regexl
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// printerMaxLineLen is the line length after which function arguments and '+'/'or' chains are split over multiple lines
+	printerMaxLineLen = 80
+	printerTabWidth   = 4
+)
+
+// Format returns the query in the canonical Regexl style with its comments kept. See Ast.Format for the style rules
+func Format(query string) (string, error) {
+
+	ast, err := NewRegexl(query).Parse()
+	if err != nil {
+		return "", err
+	}
+
+	return ast.Format(), nil
+}
+
+// Format returns the AST as Regexl source in the canonical style, which is:
+//   - Top level nodes (e.g. set_options and select) are separated by an empty line, and the source ends with a new line
+//   - Objects (e.g. of set_options) have one key-value pair per line, each ending with a comma
+//   - A select, function call or '+'/'or' chain stays on one line if it fits in 80 columns (a tab counts as 4), otherwise each select expression,
+//     function argument and chain part is put on its own line, indented by one more tab. The alternation operator '|' is written as 'or'
+//   - Comments at the end of a line stay there and other comments are put on their own line before the node they are attached to.
+//     A node with comments inside it is never put on one line
+//
+// Formatting already formatted source doesn't change it
+func (a *Ast) Format() string {
+
+	p := &printer{comments: a.Comments}
+
+	sb := strings.Builder{}
+	for i, n := range a.Nodes {
+
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(p.leadingComments(n, 0))
+		sb.WriteString(p.format(n, 0))
+		sb.WriteString(p.trailingComments(n, 0))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+type printer struct {
+	comments map[Node][]Comment
+}
+
+// format writes the node on one line if it fits, otherwise the node is split over multiple lines.
+// The first line isn't indented, as it continues the current line
+func (p *printer) format(n Node, indent int) string {
+
+	inline, ok := p.formatInline(n)
+	if ok && indent*printerTabWidth+len(inline) <= printerMaxLineLen {
+		return inline
+	}
+
+	indentString := strings.Repeat("\t", indent)
+	innerIndentString := indentString + "\t"
+
+	sb := strings.Builder{}
+	switch typedNode := n.(type) {
+
+	case *SelectStmt:
+
+		sb.WriteString("select")
+		for _, e := range typedNode.Es {
+			sb.WriteString("\n" + p.leadingComments(e, indent+1) + innerIndentString + p.format(e, indent+1) + p.trailingComments(e, indent+1))
+		}
+
+	case *BinaryExpr:
+
+		// Put each part of the '+' or 'or' chain on its own line
+		parts := binaryExprChain(typedNode)
+		for i, part := range parts {
+
+			if i > 0 {
+				sb.WriteString("\n" + p.leadingComments(part, indent) + indentString)
+			}
+
+			sb.WriteString(p.format(part, indent))
+			if i < len(parts)-1 {
+				sb.WriteString(" " + typedNode.Type.operator())
+			}
+
+			sb.WriteString(p.trailingComments(part, indent))
+		}
+
+	case *FuncExpr:
+
+		// A single object argument (e.g. of set_options) is written as 'name({' so the object isn't indented twice
+		if len(typedNode.Args) == 1 && len(p.comments[typedNode.Args[0]]) == 0 {
+
+			if obj, ok := typedNode.Args[0].(*ObjectLiteralExpr); ok {
+				return typedNode.Ident.Name + "(" + p.format(obj, indent) + ")"
+			}
+		}
+
+		sb.WriteString(typedNode.Ident.Name + "(\n")
+		for i, arg := range typedNode.Args {
+
+			sb.WriteString(p.leadingComments(arg, indent+1) + innerIndentString + p.format(arg, indent+1))
+			if i < len(typedNode.Args)-1 {
+				sb.WriteString(",")
+			}
+
+			sb.WriteString(p.trailingComments(arg, indent+1) + "\n")
+		}
+
+		sb.WriteString(indentString + ")")
+
+	case *ObjectLiteralExpr:
+
+		sb.WriteString("{\n")
+		for i := 0; i < len(typedNode.KeyVals); i++ {
+
+			kv := &typedNode.KeyVals[i]
+			sb.WriteString(p.leadingComments(kv, indent+1) + innerIndentString + kv.Key.Name + ": " + p.format(kv.Val, indent+1) + ",")
+			sb.WriteString(p.trailingComments(kv, indent+1) + "\n")
+		}
+
+		sb.WriteString(indentString + "}")
+
+	default:
+		return inline
+	}
+
+	return sb.String()
+}
+
+// formatInline writes the node on one line. False is returned if the node can't be on one line, which is the case
+// if it has comments inside it or if it has an object with key-value pairs
+func (p *printer) formatInline(n Node) (string, bool) {
+
+	switch typedNode := n.(type) {
+
+	case *SelectStmt:
+
+		es, ok := p.formatInlineList(typedNode.Es, " ")
+		if es == "" {
+			return "select", ok
+		}
+
+		return "select " + es, ok
+
+	case *BinaryExpr:
+		return p.formatInlineList(binaryExprChain(typedNode), " "+typedNode.Type.operator()+" ")
+
+	case *FuncExpr:
+		args, ok := p.formatInlineList(typedNode.Args, ", ")
+		return typedNode.Ident.Name + "(" + args + ")", ok
+
+	case *ObjectLiteralExpr:
+		return "{}", len(typedNode.KeyVals) == 0
+
+	case *LiteralExpr:
+
+		// Strings are kept as written (e.g. raw strings stay raw), and strings not parsed from a query are quoted
+		if typedNode.Type == TokenType_String {
+
+			if typedNode.Raw != "" {
+				return typedNode.Raw, true
+			}
+
+			return quoteString(typedNode.Value), true
+		}
+
+		return typedNode.Value, true
+
+	default:
+		panic(fmt.Sprintf("unhandled node type in printer.formatInline. Node=%+v", n))
+	}
+}
+
+func (p *printer) formatInlineList(exprs []Expr, sep string) (string, bool) {
+
+	isInline := true
+	strs := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+
+		s, ok := p.formatInline(e)
+		if !ok || len(p.comments[e]) > 0 {
+			isInline = false
+		}
+
+		strs = append(strs, s)
+	}
+
+	return strings.Join(strs, sep), isInline
+}
+
+// leadingComments returns the comments before the node, each on its own line and ending with a new line
+func (p *printer) leadingComments(n Node, indent int) string {
+
+	sb := strings.Builder{}
+	for _, c := range p.comments[n] {
+
+		if !c.IsTrailing {
+			sb.WriteString(strings.Repeat("\t", indent) + "//" + c.Text + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// trailingComments returns the comments after the node, where a comment that was on its own line stays on its own line
+func (p *printer) trailingComments(n Node, indent int) string {
+
+	sb := strings.Builder{}
+	for _, c := range p.comments[n] {
+
+		if !c.IsTrailing {
+			continue
+		}
+
+		if c.IsOwnLine {
+			sb.WriteString("\n" + strings.Repeat("\t", indent) + "//" + c.Text)
+			continue
+		}
+
+		sb.WriteString(" //" + c.Text)
+	}
+
+	return sb.String()
+}
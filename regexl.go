@@ -1,22 +1,25 @@
 package regexl
 
 import (
-	"encoding/json"
 	"fmt"
 	"regexp"
 )
 
-// @TODO: remove or make something nicer
-// Debug options
-var (
-	PrintTokens  bool
-	PrintAstJson bool
-	PrintAstTree bool
-)
-
 type Regexl struct {
-	Query          string
+	Query string
+
+	// CompiledRegexp is the compiled Go regex, and is only set when the last successful compile was for the Go dialect
 	CompiledRegexp *regexp.Regexp
+	// Opts are the options set by set_options, and like CompiledRegexp are only set when the last successful compile was for the Go dialect.
+	// FindAllMatches tells whether CompiledRegexp should be used with functions like Regexp.FindAllString
+	Opts RegexOptions
+
+	// Dialect is the regex dialect used in the last successful compile
+	Dialect string
+	// CompiledString is the regex string produced by the last successful compile
+	CompiledString string
+	// Diagnostics are the non-fatal notes produced by the backend in the last successful compile
+	Diagnostics []Diagnostic
 }
 
 func NewRegexl(query string) *Regexl {
@@ -28,61 +31,121 @@ func NewRegexl(query string) *Regexl {
 	return rl
 }
 
-// Compile tries to compile the query within this Regexl object and then sets Regexl.CompiledRegexp.
+// Compile tries to compile the query within this Regexl object into a Go regex and then sets Regexl.CompiledRegexp.
 // Regexl.CompiledRegexp is only set if no error is found, otherwise the error is returned and Regexl.CompiledRegexp is unchanged.
+//
+// Compile is equivalent to Regexl.CompileFor(Dialect_Go).
 func (rl *Regexl) Compile() error {
+	return rl.CompileFor(Dialect_Go)
+}
 
-	parser := NewParser(rl.Query)
+// CompileFor tries to compile the query within this Regexl object into a regex of the passed dialect (e.g. Dialect_Go),
+// and then sets Regexl.Dialect, Regexl.CompiledString and Regexl.Diagnostics. If the dialect is Dialect_Go then Regexl.CompiledRegexp is set as well,
+// otherwise it is set to nil. Regexl.Opts is set and cleared in the same way.
+// These fields are only set if no error is found, otherwise the error is returned and the fields are unchanged.
+func (rl *Regexl) CompileFor(dialect string) error {
 
-	// Tokenize
-	tokens, err := parser.Tokenize()
+	backend, err := NewBackend(dialect)
 	if err != nil {
 		return err
 	}
 
-	if PrintTokens {
+	regexString, diags, err := rl.compile(backend)
+	if err != nil {
+		return err
+	}
 
-		b, err := json.MarshalIndent(tokens, "", "  ")
+	// CompiledRegexp and Opts are cleared for other dialects, so that they never hold the results of an earlier compile
+	var goRegexp *regexp.Regexp
+	var opts RegexOptions
+	if dialect == Dialect_Go {
+
+		goRegexp, err = regexp.Compile(regexString)
 		if err != nil {
-			return err
+			return fmt.Errorf("compiling regexp failed. Query=%s; Err=%s", regexString, err.Error())
 		}
 
-		fmt.Printf("%d Tokens: %s\n", len(tokens), string(b))
+		if gb, ok := backend.(*GoBackend); ok {
+			opts = gb.Opts
+		}
 	}
 
-	if len(tokens) == 0 {
-		return fmt.Errorf("empty query is not allowed")
+	rl.CompiledRegexp = goRegexp
+	rl.Opts = opts
+	rl.Dialect = dialect
+	rl.CompiledString = regexString
+	rl.Diagnostics = diags
+	return nil
+}
+
+// CompileString compiles the query within this Regexl object into a regex string of the passed dialect and returns it.
+// Unlike Regexl.CompileFor, the Regexl object is not changed.
+func (rl *Regexl) CompileString(dialect string) (string, error) {
+
+	backend, err := NewBackend(dialect)
+	if err != nil {
+		return "", err
 	}
 
-	// Gen AST
-	ast := NewAst(tokens)
-	err = ast.Gen()
+	regexString, _, err := rl.compile(backend)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if PrintAstJson {
+	return regexString, nil
+}
 
-		b, err := json.MarshalIndent(ast.Nodes, "", "  ")
-		if err != nil {
-			return err
-		}
+func (rl *Regexl) compile(backend Backend) (regexString string, diags []Diagnostic, err error) {
 
-		fmt.Printf("AST JSON: %s\n", b)
+	// The checker and backend still check the nodes that were parsed, so that one compile reports as many errors as possible
+	ast, parseErr := rl.Parse()
+	if ast == nil || (parseErr != nil && len(ast.Nodes) == 0) {
+		return "", nil, parseErr
 	}
 
-	if PrintAstTree {
-		ast.PrintTree()
+	checkErr := NewChecker(ast).Check()
+	regexString, diags, err = backend.AstToRegexString(ast)
+	if parseErr == nil && checkErr == nil && err == nil {
+		return regexString, diags, nil
 	}
 
-	gb := &GoBackend{}
-	goRegexp, _, err := gb.AstToGoRegex(ast)
-	if err != nil {
-		return err
+	// The checker finds most of what the backend does, so its errors come first to be the ones kept by RemoveMultiples
+	errs := ErrorList{}
+	errs.Add(parseErr)
+	errs.Add(checkErr)
+	errs.Add(err)
+	errs.RemoveMultiples()
+	setErrorsQuery(errs, rl.Query)
+	return "", nil, errs
+}
+
+// Parse tokenizes the query within this Regexl object and generates its AST, which is what compiling does before passing the AST to a backend.
+// The tokens are available in Ast.Tokens.
+//
+// Parsing continues after errors, so the returned error is an ErrorList with all the errors found. The AST is returned even if there are errors,
+// in which case it has the nodes that could be parsed. Nil is only returned for an empty query
+func (rl *Regexl) Parse() (*Ast, error) {
+
+	tokens, tokensErr := NewParser(rl.Query).Tokenize()
+	if len(tokens) == 0 {
+
+		if tokensErr != nil {
+			return nil, tokensErr
+		}
+
+		return nil, fmt.Errorf("empty query is not allowed")
 	}
 
-	rl.CompiledRegexp = goRegexp
-	return nil
+	ast := NewAst(tokens)
+	astErr := ast.Gen()
+
+	// Problems like unclosed brackets are found by both the tokenizer and Gen
+	errs := ErrorList{}
+	errs.Add(tokensErr)
+	errs.Add(astErr)
+	errs.RemoveMultiples()
+	setErrorsQuery(errs, rl.Query)
+	return ast, errs.Err()
 }
 
 // MustCompile compiles the query within this regexl object by calling Regexl.Compile and panics if an error is thrown
@@ -0,0 +1,141 @@
+package regexl
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Backend turns an AST into a regex string of a specific dialect (e.g. Go, JavaScript, Python, etc).
+//
+// Backends are stateful (e.g. options set by set_options are stored on the backend), so a new backend
+// should be used per compile, which is what NewBackend does.
+type Backend interface {
+	// Dialect returns the name of the regex dialect produced by this backend, which is also the name it is registered with
+	Dialect() string
+
+	// AstToRegexString produces a regex string of this backend's dialect from the passed AST.
+	// Diagnostics are non-fatal notes about the produced regex (e.g. a construct that was translated in a lossy way),
+	// while an error means no valid regex could be produced.
+	AstToRegexString(ast *Ast) (regexString string, diags []Diagnostic, err error)
+}
+
+// Diagnostic is a non-fatal note produced by a backend about a specific part of the query
+type Diagnostic struct {
+	Pos TokenPos
+	Msg string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("loc=%d; %s", d.Pos, d.Msg)
+}
+
+var _ error = &BackendError{}
+
+// BackendError is an error produced by a backend about a specific part of the query
+type BackendError struct {
+	Err error
+	Pos TokenPos
+	// End is the position after the last byte of what caused the error. If End isn't after Pos the error is about the character at Pos
+	End TokenPos
+	// FuncName is the name of the function whose call caused the error (e.g. 'starts_with'), and is empty if the error isn't about a function call
+	FuncName string
+
+	// query is set by setErrorsQuery
+	query string
+}
+
+func (be *BackendError) Span() (start, end TokenPos) {
+	return errorSpan(be.Pos, be.End)
+}
+
+func (be *BackendError) Unwrap() error {
+	return be.Err
+}
+
+func (be *BackendError) setQuery(query string) {
+	be.query = query
+}
+
+func (be *BackendError) Error() string {
+
+	if be == nil || be.Err == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("backend error: loc=%s; err=%s", errorLoc(be.query, be.Pos), be.Err.Error())
+}
+
+// funcError returns a BackendError about the whole call of the function
+func funcError(fExpr *FuncExpr, format string, args ...any) *BackendError {
+	return argError(fExpr, fExpr, format, args...)
+}
+
+// argError returns a BackendError about a part of the call of the function, usually one of its arguments
+func argError(fExpr *FuncExpr, n Node, format string, args ...any) *BackendError {
+	return &BackendError{
+		Err:      fmt.Errorf(format, args...),
+		Pos:      n.StartPos(),
+		End:      n.EndPos(),
+		FuncName: fExpr.Ident.Name,
+	}
+}
+
+// argCountError returns the error of a function being passed the wrong number of arguments, where expected is what the function takes (e.g. "two arguments")
+func argCountError(fExpr *FuncExpr, expected string) *BackendError {
+	return funcError(fExpr, "function '%s' must have %s but was passed %d arguments", fExpr.Ident.Name, expected, len(fExpr.Args))
+}
+
+// BackendFactory creates a new instance of a backend
+type BackendFactory func() Backend
+
+var (
+	backendsMutex sync.RWMutex
+	backends      = map[string]BackendFactory{}
+)
+
+// RegisterBackend makes a backend available under the passed dialect name, which can then be used with NewBackend and Regexl.CompileFor.
+// Registering a dialect name that is already registered replaces the old backend.
+func RegisterBackend(dialect string, factory BackendFactory) {
+
+	if dialect == "" {
+		panic("can't register a backend with an empty dialect name")
+	}
+
+	if factory == nil {
+		panic(fmt.Sprintf("can't register a nil backend factory for the dialect '%s'", dialect))
+	}
+
+	backendsMutex.Lock()
+	backends[dialect] = factory
+	backendsMutex.Unlock()
+}
+
+// NewBackend returns a new instance of the backend registered under the passed dialect name
+func NewBackend(dialect string) (Backend, error) {
+
+	backendsMutex.RLock()
+	factory, ok := backends[dialect]
+	backendsMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no backend is registered for the regex dialect '%s'. Registered dialects: %v", dialect, Dialects())
+	}
+
+	return factory(), nil
+}
+
+// Dialects returns a sorted list of all registered dialect names
+func Dialects() []string {
+
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	dialects := make([]string, 0, len(backends))
+	for dialect := range backends {
+		dialects = append(dialects, dialect)
+	}
+
+	slices.Sort(dialects)
+	return dialects
+}
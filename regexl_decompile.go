@@ -0,0 +1,522 @@
+package regexl
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Decompile converts a Go regex (e.g. '(?i)^hello') into a Regexl query (e.g. "select starts_with('hello')") that produces an equivalent regex.
+//
+// An error is returned if the regex uses features that can't be expressed in Regexl (e.g. word boundaries), or if the produced query
+// doesn't compile to a regex equivalent to the passed one, which makes sure that the produced query can be trusted
+func Decompile(pattern string) (string, error) {
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("parsing regex failed. Regex=%s; Err=%s", pattern, err.Error())
+	}
+
+	d := &decompiler{}
+	d.isCaseSensitive = d.caseSensitivity(re)
+
+	expr, err := d.regexpToExpr(re)
+	if err != nil {
+		return "", err
+	}
+
+	query := d.query(expr)
+
+	// Make sure the query gives back the same regex
+	regexString, err := NewRegexl(query).CompileString(Dialect_Go)
+	if err != nil {
+		return "", fmt.Errorf("decompiled query failed to compile, so the regex can't be expressed in Regexl. Regex=%s; Query=%s; Err=%s", pattern, query, err.Error())
+	}
+
+	compiledRe, err := syntax.Parse(regexString, syntax.Perl)
+	if err != nil || !normalizeFoldCase(compiledRe.Simplify()).Equal(normalizeFoldCase(re.Simplify())) {
+		return "", fmt.Errorf("decompiled query produces a regex different from the original, so the regex can't be expressed in Regexl. Regex=%s; Produced regex=%s; Query=%s", pattern, regexString, query)
+	}
+
+	return query, nil
+}
+
+type decompiler struct {
+	isCaseSensitive bool
+}
+
+// caseSensitivity returns whether the query is case sensitive, which is taken from the first part of the regex that has letters.
+// Parts with a different case sensitivity (e.g. the 'b' of 'a(?i:b)') are put in with_options by caseScoped
+func (d *decompiler) caseSensitivity(re *syntax.Regexp) bool {
+
+	var walk func(re *syntax.Regexp) (isCaseSensitive, isFound bool)
+	walk = func(re *syntax.Regexp) (isCaseSensitive, isFound bool) {
+
+		if (re.Op == syntax.OpLiteral || re.Op == syntax.OpCharClass) && hasCaseVariants(re) {
+			return re.Flags&syntax.FoldCase == 0, true
+		}
+
+		for _, sub := range re.Sub {
+
+			if isCaseSensitive, isFound := walk(sub); isFound {
+				return isCaseSensitive, true
+			}
+		}
+
+		return false, false
+	}
+
+	isCaseSensitive, _ := walk(re)
+	return isCaseSensitive
+}
+
+// caseScoped puts the expression of a literal or character class in with_options if its case sensitivity differs from the one of the query
+func (d *decompiler) caseScoped(re *syntax.Regexp, expr Expr) Expr {
+
+	isCaseSensitive := re.Flags&syntax.FoldCase == 0
+	if isCaseSensitive == d.isCaseSensitive || !hasCaseVariants(re) {
+		return expr
+	}
+
+	return newDecompiledFunc("with_options", caseSensitivityOption(isCaseSensitive), expr)
+}
+
+func (d *decompiler) regexpToExpr(re *syntax.Regexp) (Expr, error) {
+
+	switch re.Op {
+
+	case syntax.OpEmptyMatch:
+		return newDecompiledString(""), nil
+
+	case syntax.OpLiteral:
+
+		expr, err := d.literalToExpr(re)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.caseScoped(re, expr), nil
+
+	case syntax.OpCharClass:
+
+		expr, err := d.charClassToExpr(re)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.caseScoped(re, expr), nil
+
+	case syntax.OpAnyCharNotNL:
+		return newDecompiledFunc("any_char"), nil
+
+	// '.' with the 's' flag matches new lines as well
+	case syntax.OpAnyChar:
+		return newDecompiledFunc("with_options", dotMatchesNewlineOption(), newDecompiledFunc("any_char")), nil
+
+	case syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine:
+		return newDecompiledFunc(anchorFunc(re), newDecompiledString("")), nil
+
+	case syntax.OpCapture:
+
+		sub, err := d.regexpToExpr(re.Sub[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if re.Name == "" {
+			return newDecompiledFunc("capture", sub), nil
+		}
+
+		return newDecompiledFunc("capture_as", newDecompiledString(re.Name), sub), nil
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		return d.repeatToExpr(re)
+
+	case syntax.OpConcat:
+		return d.concatToExpr(re)
+
+	case syntax.OpAlternate:
+
+		args := make([]Expr, 0, len(re.Sub))
+		for _, sub := range re.Sub {
+
+			subExpr, err := d.regexpToExpr(sub)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, subExpr)
+		}
+
+		return newDecompiledFunc("any_strings_of", args...), nil
+
+	default:
+		return nil, fmt.Errorf("regex operator '%s' can't be expressed in Regexl. Regex=%s", re.Op, re)
+	}
+}
+
+func (d *decompiler) literalToExpr(re *syntax.Regexp) (Expr, error) {
+
+	runes := re.Rune
+	if re.Flags&syntax.FoldCase != 0 {
+
+		// The parser turns case insensitive literals into their upper case form, but lower case is easier to read
+		runes = make([]rune, len(re.Rune))
+		for i, r := range re.Rune {
+			runes[i] = unicode.ToLower(r)
+		}
+	}
+
+	return newDecompiledString(string(runes)), nil
+}
+
+func (d *decompiler) charClassToExpr(re *syntax.Regexp) (Expr, error) {
+
+	// Sets ending at the last rune are negated sets (e.g. '[^a]'), which are written as none_of_chars of the characters they don't match
+	ranges := re.Rune
+	isNegated := len(ranges) > 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if isNegated {
+		ranges = negateRanges(ranges)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("character sets matching every character can't be expressed in Regexl. Regex=%s", re)
+	}
+
+	if re.Flags&syntax.FoldCase != 0 {
+		ranges = unfoldRanges(ranges)
+	}
+
+	chars := strings.Builder{}
+	args := make([]Expr, 0, len(ranges)/2)
+	for i := 0; i < len(ranges); i += 2 {
+
+		from, to := ranges[i], ranges[i+1]
+		if from == to {
+			chars.WriteRune(from)
+			continue
+		}
+
+		// Single characters are nicer as part of one string, unless the range is just two characters
+		if to == from+1 {
+			chars.WriteRune(from)
+			chars.WriteRune(to)
+			continue
+		}
+
+		fromString, toString := string(from), string(to)
+		if isAsciiUpper(from) && isAsciiUpper(to) && re.Flags&syntax.FoldCase != 0 {
+			fromString, toString = strings.ToLower(fromString), strings.ToLower(toString)
+		}
+
+		args = append(args, newDecompiledFunc("from_to", newDecompiledString(fromString), newDecompiledString(toString)))
+	}
+
+	if chars.Len() > 0 {
+
+		s := chars.String()
+		if re.Flags&syntax.FoldCase != 0 {
+			s = strings.ToLower(s)
+		}
+
+		args = append(args, newDecompiledString(s))
+	}
+
+	if isNegated {
+		return newDecompiledFunc("none_of_chars", args...), nil
+	}
+
+	return newDecompiledFunc("any_chars_of", args...), nil
+}
+
+// negateRanges returns the ranges of the runes that aren't in the passed sorted ranges
+func negateRanges(ranges []rune) []rune {
+
+	negated := make([]rune, 0, len(ranges)+2)
+	next := rune(0)
+	for i := 0; i < len(ranges); i += 2 {
+
+		if ranges[i] > next {
+			negated = append(negated, next, ranges[i]-1)
+		}
+
+		next = ranges[i+1] + 1
+	}
+
+	if next <= unicode.MaxRune {
+		negated = append(negated, next, unicode.MaxRune)
+	}
+
+	return negated
+}
+
+func (d *decompiler) repeatToExpr(re *syntax.Regexp) (Expr, error) {
+
+	// '.*' has its own function
+	isLazy := re.Flags&syntax.NonGreedy != 0
+	if re.Op == syntax.OpStar && re.Sub[0].Op == syntax.OpAnyCharNotNL && !isLazy {
+		return newDecompiledFunc("any_chars"), nil
+	}
+
+	sub, err := d.regexpToExpr(re.Sub[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if isLazy {
+		return d.lazyRepeatToExpr(re, sub)
+	}
+
+	switch {
+
+	case re.Op == syntax.OpStar || (re.Op == syntax.OpRepeat && re.Min == 0 && re.Max == -1):
+		return newDecompiledFunc("zero_plus_of", sub), nil
+
+	case re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Min == 1 && re.Max == -1):
+		return newDecompiledFunc("one_plus_of", sub), nil
+
+	case re.Op == syntax.OpQuest:
+		return newDecompiledFunc("optional", sub), nil
+
+	case re.Min == re.Max:
+		return newDecompiledFunc("exactly", sub, newDecompiledInt(re.Min)), nil
+
+	case re.Max == -1:
+		return newDecompiledFunc("at_least", sub, newDecompiledInt(re.Min)), nil
+
+	case re.Min == 0:
+		return newDecompiledFunc("at_most", sub, newDecompiledInt(re.Max)), nil
+
+	default:
+		return newDecompiledFunc("count_between", sub, newDecompiledInt(re.Min), newDecompiledInt(re.Max)), nil
+	}
+}
+
+// lazyRepeatToExpr converts a lazy repeat (e.g. 'a+?') into one of the lazy functions, where forms without their own function (e.g. 'a??') use lazy_count_between
+func (d *decompiler) lazyRepeatToExpr(re *syntax.Regexp, sub Expr) (Expr, error) {
+
+	switch {
+
+	case re.Op == syntax.OpStar || (re.Op == syntax.OpRepeat && re.Min == 0 && re.Max == -1):
+		return newDecompiledFunc("lazy_zero_plus_of", sub), nil
+
+	case re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Min == 1 && re.Max == -1):
+		return newDecompiledFunc("lazy_one_plus_of", sub), nil
+
+	case re.Op == syntax.OpQuest:
+		return newDecompiledFunc("lazy_count_between", sub, newDecompiledInt(0), newDecompiledInt(1)), nil
+
+	// '{n}?' matches the same as '{n}'
+	case re.Min == re.Max:
+		return newDecompiledFunc("exactly", sub, newDecompiledInt(re.Min)), nil
+
+	case re.Max != -1:
+		return newDecompiledFunc("lazy_count_between", sub, newDecompiledInt(re.Min), newDecompiledInt(re.Max)), nil
+
+	default:
+		return nil, fmt.Errorf("lazy repeats without a maximum like '{%d,}?' can't be expressed in Regexl. Regex=%s", re.Min, re)
+	}
+}
+
+// anchorFunc returns the function writing the anchor re, where '^' and '$' of the multiline flag are line anchors.
+// '\z' is text_ends_with as '$' without the multiline flag is parsed differently, even though the two match the same
+func anchorFunc(re *syntax.Regexp) string {
+
+	switch {
+	case re.Op == syntax.OpBeginLine:
+		return "line_starts_with"
+	case re.Op == syntax.OpEndLine:
+		return "line_ends_with"
+	case re.Op == syntax.OpEndText && re.Flags&syntax.WasDollar == 0:
+		return "text_ends_with"
+	case re.Op == syntax.OpEndText:
+		return "ends_with"
+	default:
+		return "starts_with"
+	}
+}
+
+// concatToExpr joins the parts of the concatenation with '+', where '^' and '$' are turned into starts_with and ends_with calls on their neighbours
+func (d *decompiler) concatToExpr(re *syntax.Regexp) (Expr, error) {
+
+	exprs := make([]Expr, 0, len(re.Sub))
+	for i := 0; i < len(re.Sub); i++ {
+
+		sub := re.Sub[i]
+		switch {
+
+		case (sub.Op == syntax.OpBeginText || sub.Op == syntax.OpBeginLine) && i+1 < len(re.Sub):
+
+			next, err := d.regexpToExpr(re.Sub[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, newDecompiledFunc(anchorFunc(sub), next))
+			i++
+
+		case (sub.Op == syntax.OpEndText || sub.Op == syntax.OpEndLine) && len(exprs) > 0:
+			exprs[len(exprs)-1] = newDecompiledFunc(anchorFunc(sub), exprs[len(exprs)-1])
+
+		default:
+
+			expr, err := d.regexpToExpr(sub)
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, expr)
+		}
+	}
+
+	// Chain with '+' in the same way the AST does, where the right side holds the rest of the chain
+	expr := exprs[len(exprs)-1]
+	for i := len(exprs) - 2; i >= 0; i-- {
+		expr = &BinaryExpr{
+			Type: TokenType_Plus,
+			Lhs:  exprs[i],
+			Rhs:  expr,
+		}
+	}
+
+	return expr, nil
+}
+
+// query returns the query of the expression, formatted in the canonical style
+func (d *decompiler) query(expr Expr) string {
+
+	ast := &Ast{Nodes: make([]Node, 0, 2)}
+	if d.isCaseSensitive {
+
+		ast.Nodes = append(ast.Nodes, newDecompiledFunc("set_options", caseSensitivityOption(true)))
+	}
+
+	ast.Nodes = append(ast.Nodes, &SelectStmt{Type: TokenType_Keyword, Es: []Expr{expr}})
+	return ast.Format()
+}
+
+// caseSensitivityOption is the options object setting case_sensitive (e.g. '{case_sensitive: true}')
+func caseSensitivityOption(isCaseSensitive bool) *ObjectLiteralExpr {
+	return &ObjectLiteralExpr{
+		KeyVals: []KeyValExpr{{
+			Key: IdentExpr{Name: "case_sensitive"},
+			Val: &LiteralExpr{Type: TokenType_Bool, Value: strconv.FormatBool(isCaseSensitive)},
+		}},
+	}
+}
+
+// dotMatchesNewlineOption is the options object '{dot_matches_newline: true}'
+func dotMatchesNewlineOption() *ObjectLiteralExpr {
+	return &ObjectLiteralExpr{
+		KeyVals: []KeyValExpr{{
+			Key: IdentExpr{Name: "dot_matches_newline"},
+			Val: &LiteralExpr{Type: TokenType_Bool, Value: "true"},
+		}},
+	}
+}
+
+func newDecompiledFunc(name string, args ...Expr) *FuncExpr {
+	return &FuncExpr{
+		Ident: IdentExpr{Name: name},
+		Args:  args,
+	}
+}
+
+func newDecompiledString(s string) *LiteralExpr {
+	return &LiteralExpr{
+		Type:  TokenType_String,
+		Value: s,
+	}
+}
+
+func newDecompiledInt(i int) *LiteralExpr {
+	return &LiteralExpr{
+		Type:  TokenType_Int,
+		Value: strconv.Itoa(i),
+	}
+}
+
+// hasCaseVariants returns true if any of the characters matched by the literal or character class have other cases
+func hasCaseVariants(re *syntax.Regexp) bool {
+
+	if re.Op == syntax.OpLiteral {
+
+		for _, r := range re.Rune {
+
+			if unicode.SimpleFold(r) != r {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for i := 0; i < len(re.Rune); i += 2 {
+
+		for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+
+			if unicode.SimpleFold(r) != r {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unfoldRanges takes the ranges of a case insensitive character class, which the parser fills with all cases of each character,
+// and keeps only the smallest case of each character (e.g. 'A-Za-z' becomes 'A-Z'), which matches the same characters when case insensitive
+func unfoldRanges(ranges []rune) []rune {
+
+	unfolded := make([]rune, 0, len(ranges))
+	for i := 0; i < len(ranges); i += 2 {
+
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+
+			if minFoldRune(r) != r {
+				continue
+			}
+
+			if len(unfolded) > 0 && unfolded[len(unfolded)-1] == r-1 {
+				unfolded[len(unfolded)-1] = r
+				continue
+			}
+
+			unfolded = append(unfolded, r, r)
+		}
+	}
+
+	return unfolded
+}
+
+// normalizeFoldCase removes the case insensitive flag from literals and character classes that have no letters, where it has no effect.
+// This allows comparing regexes where the only difference is the case sensitivity of things like digits
+func normalizeFoldCase(re *syntax.Regexp) *syntax.Regexp {
+
+	if (re.Op == syntax.OpLiteral || re.Op == syntax.OpCharClass) && !hasCaseVariants(re) {
+		re.Flags &^= syntax.FoldCase
+	}
+
+	for _, sub := range re.Sub {
+		normalizeFoldCase(sub)
+	}
+
+	return re
+}
+
+// minFoldRune returns the smallest character that is equal to r under case folding
+func minFoldRune(r rune) rune {
+
+	smallest := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		smallest = min(smallest, f)
+	}
+
+	return smallest
+}
+
+func isAsciiUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
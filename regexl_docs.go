@@ -0,0 +1,285 @@
+package regexl
+
+// BuiltinDoc documents a built-in function or set_options key, and is used by tools like the language server for hover docs and completion
+type BuiltinDoc struct {
+	Name string
+	// Signature shows how the built-in is used (e.g. "count_between(x, min, max)" or "case_sensitive: bool")
+	Signature string
+	Doc       string
+}
+
+// BuiltinFuncs documents the functions that can be used in queries
+var BuiltinFuncs = []BuiltinDoc{
+	{
+		Name:      "set_options",
+		Signature: "set_options({key: value, ...})",
+		Doc:       "Sets the options of the query using an object (e.g. `set_options({case_sensitive: true})`). Must be at the top level of the query.",
+	},
+	{
+		Name:      "with_options",
+		Signature: "with_options({key: value, ...}, x)",
+		Doc:       "Matches `x` with the passed options changed for it only, like `(?i:x)` or `(?-i:x)`. Takes the same options as `set_options` except `find_all_matches`.",
+	},
+	{
+		Name:      "any_strings_of",
+		Signature: "any_strings_of(x, ...)",
+		Doc:       "Matches any one of the passed expressions, like `x|y`, which is grouped as `(?:x|y)` when joined with other expressions. Same as `x or y`.",
+	},
+	{
+		Name:      "any_chars_of",
+		Signature: "any_chars_of(chars or from_to(from, to) or class, ...)",
+		Doc:       "Matches one character that is in any of the passed strings, `from_to` ranges or classes (e.g. `digit()` or `unicode_category('L')`), like `[abc]`, `[a-z]` or `[\\d_]`.",
+	},
+	{
+		Name:      "none_of_chars",
+		Signature: "none_of_chars(chars or from_to(from, to) or class, ...)",
+		Doc:       "Matches one character that is not in any of the passed strings, `from_to` ranges or classes, like `[^abc]`. Takes the same arguments as `any_chars_of`.",
+	},
+	{
+		Name:      "from_to",
+		Signature: "from_to(from, to)",
+		Doc:       "A range of characters from `from` to `to` (inclusive) passed to `any_chars_of`, like `a-z` in `[a-z]`. Both ends must be single characters.",
+	},
+	{
+		Name:      "starts_with",
+		Signature: "starts_with(x)",
+		Doc:       "Matches `x` only at the start of the text, or of a line if the `multiline` option is set, like `^x`.",
+	},
+	{
+		Name:      "ends_with",
+		Signature: "ends_with(x)",
+		Doc:       "Matches `x` only at the end of the text, or of a line if the `multiline` option is set, like `x$`.",
+	},
+	{
+		Name:      "line_starts_with",
+		Signature: "line_starts_with(x)",
+		Doc:       "Matches `x` only at the start of a line, whether the `multiline` option is set or not, like `(?m:^)x`.",
+	},
+	{
+		Name:      "line_ends_with",
+		Signature: "line_ends_with(x)",
+		Doc:       "Matches `x` only at the end of a line, whether the `multiline` option is set or not, like `x(?m:$)`.",
+	},
+	{
+		Name:      "text_starts_with",
+		Signature: "text_starts_with(x)",
+		Doc:       "Matches `x` only at the start of the text, whether the `multiline` option is set or not, like `\\Ax`.",
+	},
+	{
+		Name:      "text_ends_with",
+		Signature: "text_ends_with(x)",
+		Doc:       "Matches `x` only at the end of the text, whether the `multiline` option is set or not, like `x\\z`.",
+	},
+	{
+		Name:      "any_chars",
+		Signature: "any_chars()",
+		Doc:       "Matches zero or more characters except a new line, like `.*`.",
+	},
+	{
+		Name:      "any_char",
+		Signature: "any_char()",
+		Doc:       "Matches one character except a new line, like `.`.",
+	},
+	{
+		Name:      "digit",
+		Signature: "digit()",
+		Doc:       "Matches one ASCII digit, like `\\d` or `[0-9]`.",
+	},
+	{
+		Name:      "not_digit",
+		Signature: "not_digit()",
+		Doc:       "Matches one character that isn't an ASCII digit, like `\\D` or `[^0-9]`.",
+	},
+	{
+		Name:      "letter",
+		Signature: "letter()",
+		Doc:       "Matches one ASCII letter, like `[[:alpha:]]` or `[A-Za-z]`.",
+	},
+	{
+		Name:      "not_letter",
+		Signature: "not_letter()",
+		Doc:       "Matches one character that isn't an ASCII letter, like `[[:^alpha:]]` or `[^A-Za-z]`.",
+	},
+	{
+		Name:      "whitespace",
+		Signature: "whitespace()",
+		Doc:       "Matches one space, tab, new line, carriage return or form feed, like `\\s`.",
+	},
+	{
+		Name:      "not_whitespace",
+		Signature: "not_whitespace()",
+		Doc:       "Matches one character that isn't whitespace, like `\\S`.",
+	},
+	{
+		Name:      "word_char",
+		Signature: "word_char()",
+		Doc:       "Matches one ASCII letter, digit or `_`, like `\\w` or `[0-9A-Za-z_]`.",
+	},
+	{
+		Name:      "not_word_char",
+		Signature: "not_word_char()",
+		Doc:       "Matches one character that isn't an ASCII letter, digit or `_`, like `\\W`.",
+	},
+	{
+		Name:      "unicode_category",
+		Signature: "unicode_category(name)",
+		Doc:       "Matches one character of the unicode category (e.g. `'L'` for letters or `'Lu'` for upper case letters), like `\\p{Lu}`. Case categories match both cases unless `case_sensitive` is true (e.g. `'Lu'` also matches `'a'` by default). Can also be passed to `any_chars_of`. Not supported by python and posix-ere.",
+	},
+	{
+		Name:      "unicode_script",
+		Signature: "unicode_script(name)",
+		Doc:       "Matches one character of the unicode script (e.g. `'Greek'` or `'Han'`), like `\\p{Greek}`. Can also be passed to `any_chars_of`. Not supported by python and posix-ere.",
+	},
+	{
+		Name:      "not_unicode",
+		Signature: "not_unicode(unicode_category(name) or unicode_script(name))",
+		Doc:       "Matches one character that isn't in the unicode category or script, like `\\P{Lu}`. Unless `case_sensitive` is true, a case category is negated with both cases (e.g. `not_unicode(unicode_category('Lu'))` matches neither `'A'` nor `'a'` by default). Can also be passed to `any_chars_of`.",
+	},
+	{
+		Name:      "zero_plus_of",
+		Signature: "zero_plus_of(x)",
+		Doc:       "Matches `x` zero or more times, like `(?:x)*`.",
+	},
+	{
+		Name:      "one_plus_of",
+		Signature: "one_plus_of(x)",
+		Doc:       "Matches `x` one or more times, like `(?:x)+`.",
+	},
+	{
+		Name:      "count_between",
+		Signature: "count_between(x, min, max)",
+		Doc:       "Matches `x` at least `min` and at most `max` times, like `x{min,max}`. Counts must be between 0 and 1000.",
+	},
+	{
+		Name:      "optional",
+		Signature: "optional(x)",
+		Doc:       "Matches `x` zero or one time, like `x?`.",
+	},
+	{
+		Name:      "exactly",
+		Signature: "exactly(x, n)",
+		Doc:       "Matches `x` exactly `n` times, like `x{n}`. `n` must be between 0 and 1000.",
+	},
+	{
+		Name:      "at_least",
+		Signature: "at_least(x, n)",
+		Doc:       "Matches `x` `n` or more times, like `x{n,}`. `n` must be between 0 and 1000.",
+	},
+	{
+		Name:      "at_most",
+		Signature: "at_most(x, n)",
+		Doc:       "Matches `x` at most `n` times, like `x{0,n}`. `n` must be between 0 and 1000.",
+	},
+	{
+		Name:      "capture",
+		Signature: "capture(x)",
+		Doc:       "Captures the text matched by `x` in a numbered capture group, like `(x)`.",
+	},
+	{
+		Name:      "capture_as",
+		Signature: "capture_as('name', x)",
+		Doc:       "Captures the text matched by `x` in a named capture group, like `(?P<name>x)`. Names must be unique and made of letters, digits and '_'.",
+	},
+	{
+		Name:      "followed_by",
+		Signature: "followed_by(x)",
+		Doc:       "Matches if the text after this point matches `x`, without consuming it, like `(?=x)`. Needs a backtracking dialect (pcre2, python).",
+	},
+	{
+		Name:      "not_followed_by",
+		Signature: "not_followed_by(x)",
+		Doc:       "Matches if the text after this point doesn't match `x`, like `(?!x)`. Needs a backtracking dialect (pcre2, python).",
+	},
+	{
+		Name:      "preceded_by",
+		Signature: "preceded_by(x)",
+		Doc:       "Matches if the text before this point matches `x`, like `(?<=x)`. Needs a backtracking dialect (pcre2, python).",
+	},
+	{
+		Name:      "not_preceded_by",
+		Signature: "not_preceded_by(x)",
+		Doc:       "Matches if the text before this point doesn't match `x`, like `(?<!x)`. Needs a backtracking dialect (pcre2, python).",
+	},
+	{
+		Name:      "same_as_capture",
+		Signature: "same_as_capture('name')",
+		Doc:       "Matches the same text matched by an earlier `capture_as('name', ...)`, like `\\k<name>`. Needs a backtracking dialect (pcre2, python).",
+	},
+	{
+		Name:      "atomic",
+		Signature: "atomic(x)",
+		Doc:       "Matches `x` without ever backtracking into it, like `(?>x)`. Needs a backtracking dialect (pcre2, python 3.11+).",
+	},
+	{
+		Name:      "possessive_zero_plus_of",
+		Signature: "possessive_zero_plus_of(x)",
+		Doc:       "Like `zero_plus_of` but never gives back what it matched, like `(?:x)*+`. Needs a backtracking dialect (pcre2, python 3.11+).",
+	},
+	{
+		Name:      "possessive_one_plus_of",
+		Signature: "possessive_one_plus_of(x)",
+		Doc:       "Like `one_plus_of` but never gives back what it matched, like `(?:x)++`. Needs a backtracking dialect (pcre2, python 3.11+).",
+	},
+	{
+		Name:      "possessive_count_between",
+		Signature: "possessive_count_between(x, min, max)",
+		Doc:       "Like `count_between` but never gives back what it matched, like `x{min,max}+`. Needs a backtracking dialect (pcre2, python 3.11+).",
+	},
+	{
+		Name:      "lazy_zero_plus_of",
+		Signature: "lazy_zero_plus_of(x)",
+		Doc:       "Like `zero_plus_of` but matches as few times as possible, like `(?:x)*?`.",
+	},
+	{
+		Name:      "lazy_one_plus_of",
+		Signature: "lazy_one_plus_of(x)",
+		Doc:       "Like `one_plus_of` but matches as few times as possible, like `(?:x)+?`.",
+	},
+	{
+		Name:      "lazy_count_between",
+		Signature: "lazy_count_between(x, min, max)",
+		Doc:       "Like `count_between` but matches as few times as possible, like `x{min,max}?`.",
+	},
+}
+
+// BuiltinOptions documents the keys of the object passed to set_options and with_options
+var BuiltinOptions = []BuiltinDoc{
+	{
+		Name:      "case_sensitive",
+		Signature: "case_sensitive: bool",
+		Doc:       "Whether letters only match the same case. Defaults to false.",
+	},
+	{
+		Name:      "find_all_matches",
+		Signature: "find_all_matches: bool",
+		Doc:       "Whether all matches are found instead of only the first one (e.g. the 'g' flag in JavaScript). Defaults to false.",
+	},
+	{
+		Name:      "ungreedy",
+		Signature: "ungreedy: bool",
+		Doc:       "Whether greedy and lazy quantifiers are swapped, like the 'U' flag, so that `zero_plus_of` matches as little as possible. Defaults to false.",
+	},
+	{
+		Name:      "multiline",
+		Signature: "multiline: bool",
+		Doc:       "Whether `starts_with` and `ends_with` match at the start and end of each line instead of the whole text, like the 'm' flag. Defaults to false.",
+	},
+	{
+		Name:      "dot_matches_newline",
+		Signature: "dot_matches_newline: bool",
+		Doc:       "Whether `any_char` and `any_chars` also match new lines, like the 's' flag. Defaults to false.",
+	},
+}
+
+// LookupBuiltin returns the doc of the built-in of the passed name in docs (e.g. BuiltinFuncs)
+func LookupBuiltin(docs []BuiltinDoc, name string) (BuiltinDoc, bool) {
+
+	for _, doc := range docs {
+
+		if doc.Name == name {
+			return doc, true
+		}
+	}
+
+	return BuiltinDoc{}, false
+}
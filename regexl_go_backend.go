@@ -3,25 +3,147 @@ package regexl
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type RegexOptions struct {
 	CaseSensitive  bool
 	FindAllMatches bool
+	// Ungreedy swaps greedy and lazy quantifiers, so that zero_plus_of matches as little as possible and lazy_zero_plus_of as much as possible
+	Ungreedy bool
+	// Multiline makes starts_with and ends_with match at the start and end of each line instead of the whole text
+	Multiline bool
+	// DotMatchesNewline makes any_char and any_chars match new lines as well
+	DotMatchesNewline bool
 }
 
 // GoBackend produces valid Go regex strings, based on the rules here: https://pkg.go.dev/regexp/syntax
 type GoBackend struct {
 	Opts RegexOptions
+
+	// captureNames holds the names used by capture_as so far, which must be unique within a query
+	captureNames []string
+
+	// diags are the diagnostics produced so far while producing the regex
+	diags []Diagnostic
+
+	// ast is the AST the regex is being produced from
+	ast *Ast
+	// errs are the errors found so far. Walking continues after an error in a function so that all errors of the query are found
+	errs ErrorList
+
+	// syntax writes the dialect specific parts of the regex, which allows other backends (e.g. JsBackend) to reuse the AST walking and validation of GoBackend.
+	// A nil syntax means Go regex syntax
+	syntax regexSyntax
+}
+
+// regexSyntax writes the parts of a regex that differ between the dialects sharing the GoBackend AST walking.
+// Functions returning a bool return false if the dialect can't express the requested construct
+type regexSyntax interface {
+	// dialect is the name of the regex dialect of this syntax, as used with RegisterBackend
+	dialect() string
+	// escapeString escapes a literal string so that it matches itself
+	escapeString(s string) string
+	// anyChar is a regex matching any single character except a new line, or any character at all if dotMatchesNewline is set
+	anyChar(dotMatchesNewline bool) string
+	// endAnchor is a regex matching only at the end of the text
+	endAnchor() string
+	// lineAnchor is a regex matching only at the start or end of a line. isMultiline is whether the multiline flag is set, in which case '^' and '$' already match at lines
+	lineAnchor(isStart, isMultiline bool) string
+	// textAnchor is a regex matching only at the start or end of the whole text, whether the multiline flag is set or not
+	textAnchor(isStart bool) string
+	// hasLineFlags reports whether the dialect has the flags of the multiline and dot_matches_newline options (e.g. 'm' and 's')
+	hasLineFlags() bool
+	// namedCapture is a named capture group with regexString as its content
+	namedCapture(name, regexString string) (string, bool)
+	// nonCapturingGroup groups regexString without creating a capture group
+	nonCapturingGroup(regexString string) (string, bool)
+	// charSet is a regex matching any single character in the set (e.g. '[a-z_]')
+	charSet(cs *charSet) (string, bool)
+	// charClass is a regex matching any single ASCII character of the class (e.g. '\d'), or any character not in the class if negated
+	charClass(class charClass, isNegated bool) string
+	// unicodeClass is a regex matching any single character of the unicode category (e.g. 'Lu') or script (e.g. 'Greek'), or any character not in it if negated.
+	// The class must also be usable inside a character set
+	unicodeClass(name string, isScript, isNegated bool) (string, bool)
+	// lookahead is a zero width assertion that regexString matches (or doesn't match if negated) after the current position
+	lookahead(regexString string, isNegated bool) (string, bool)
+	// lookbehind is a zero width assertion that regexString matches (or doesn't match if negated) before the current position
+	lookbehind(regexString string, isNegated bool) (string, bool)
+	// lookbehindLimits returns what the dialect requires of the text matched by the regex of a lookbehind. isFixedWidth means all matches must have the same length,
+	// and maxWidth is the longest match allowed when matches have different lengths, or 0 if there is no limit
+	lookbehindLimits() (isFixedWidth bool, maxWidth int)
+	// backreference matches the same text last matched by the named capture group
+	backreference(name string) (string, bool)
+	// atomicGroup is a non-capturing group that doesn't backtrack into regexString once it matched
+	atomicGroup(regexString string) (string, bool)
+	// possessive turns the quantified greedy regexString (e.g. '(?:a)+') into a possessive one that doesn't backtrack
+	possessive(quantifiedRegexString string) (string, bool)
+	// lazy turns the quantified greedy regexString (e.g. '(?:a)+') into a lazy one that matches as little as possible
+	lazy(quantifiedRegexString string) (string, bool)
+	// hasUngreedyFlag reports whether the dialect has a flag like 'U' that swaps greedy and lazy quantifiers.
+	// Without it, the ungreedy option is applied by writing greedy quantifiers as lazy ones and the other way around
+	hasUngreedyFlag() bool
+	// scopedFlags returns the flags (e.g. 'i-s') of a '(?flags:...)' group that changes the options from outer to inner. False is returned if the dialect has no flags
+	scopedFlags(outer, inner RegexOptions) (string, bool)
+	// withOptions returns the syntax used inside a with_options call with the options, for dialects that apply options by how the regex is written instead of with flags
+	withOptions(opts RegexOptions) regexSyntax
+}
+
+const Dialect_Go = "go"
+
+func init() {
+	RegisterBackend(Dialect_Go, func() Backend { return &GoBackend{} })
+}
+
+var _ Backend = &GoBackend{}
+
+func (gb *GoBackend) Dialect() string {
+	return Dialect_Go
 }
 
+// AstToGoRegex produces a Go regex string from the AST by calling GoBackend.AstToRegexString, and then compiles it
 func (gb *GoBackend) AstToGoRegex(ast *Ast) (*regexp.Regexp, string, error) {
 
+	regexString, _, err := gb.AstToRegexString(ast)
+	if err != nil {
+		return nil, "", err
+	}
+
+	regexp, err := regexp.Compile(regexString)
+	if err != nil {
+		return regexp, regexString, fmt.Errorf("compiling regexp failed. Query=%s; Err=%s", regexString, err.Error())
+	}
+
+	return regexp, regexString, nil
+}
+
+func (gb *GoBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	regexString, diags, err := gb.astToRegexBody(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return gb.ApplyOptionsToRegexString(regexString), diags, nil
+}
+
+// astToRegexBody walks the AST and returns the regex produced by the select statement, without any options applied, and the diagnostics produced while doing so.
+// Options set by set_options are stored in GoBackend.Opts
+func (gb *GoBackend) astToRegexBody(ast *Ast) (string, []Diagnostic, error) {
+
 	if len(ast.Nodes) == 0 {
-		return nil, "", fmt.Errorf("ast must have at least one node")
+		return "", nil, fmt.Errorf("ast must have at least one node")
 	}
 
+	gb.captureNames = gb.captureNames[:0]
+	gb.diags = nil
+	gb.ast = ast
+	gb.errs = ErrorList{}
+
 	var err error
 	regexString := ""
 
@@ -32,33 +154,33 @@ func (gb *GoBackend) AstToGoRegex(ast *Ast) (*regexp.Regexp, string, error) {
 		case *FuncExpr:
 
 			if typedNode.Ident.Name != "set_options" {
-				return nil, "", fmt.Errorf("only the function 'set_options' can be used at the top level")
+				gb.errs.Add(funcError(typedNode, "only the function 'set_options' can be used at the top level"))
+				continue
 			}
 
-			_, err := gb.execFunc(typedNode)
-			if err != nil {
-				return nil, "", err
-			}
+			gb.callFunc(typedNode)
 
 		case *SelectStmt:
 
 			regexString, err = gb.nodeToGoRegex(typedNode)
 			if err != nil {
-				return nil, "", err
+				gb.errs.Add(err)
 			}
 
 		default:
-			return nil, "", fmt.Errorf("only 'select' and the 'set_options' function can be at the top level")
+			gb.errs.Add(&BackendError{
+				Err: fmt.Errorf("only 'select' and the 'set_options' function can be at the top level"),
+				Pos: typedNode.StartPos(),
+				End: typedNode.EndPos(),
+			})
 		}
 	}
 
-	regexString = gb.ApplyOptionsToRegexString(regexString)
-	regexp, err := regexp.Compile(regexString)
-	if err != nil {
-		return regexp, regexString, fmt.Errorf("compiling regexp failed. Query=%s; Err=%s", regexString, err.Error())
+	if len(gb.errs) > 0 {
+		return "", nil, gb.errs
 	}
 
-	return regexp, regexString, nil
+	return regexString, gb.diags, nil
 }
 
 func (gb *GoBackend) nodeToGoRegex(n Node) (out string, err error) {
@@ -67,9 +189,14 @@ func (gb *GoBackend) nodeToGoRegex(n Node) (out string, err error) {
 
 	case *SelectStmt:
 
+		// A single expression is the whole regex, so even an alternation doesn't need a group
+		if len(typedNode.Es) == 1 {
+			return gb.nodeToGoRegex(typedNode.Es[0])
+		}
+
 		for i := 0; i < len(typedNode.Es); i++ {
 
-			regexStr, err := gb.nodeToGoRegex(typedNode.Es[i])
+			regexStr, err := gb.concatOperand(typedNode.Es[i])
 			if err != nil {
 				return "", err
 			}
@@ -81,12 +208,28 @@ func (gb *GoBackend) nodeToGoRegex(n Node) (out string, err error) {
 
 	case *BinaryExpr:
 
-		lhsStr, err := gb.nodeToGoRegex(typedNode.Lhs)
+		// 'or' has the lowest precedence in both Regexl and regex, so only the sides of a '+' might need a group
+		if typedNode.Type == TokenType_Or {
+
+			lhsStr, err := gb.nodeToGoRegex(typedNode.Lhs)
+			if err != nil {
+				return "", err
+			}
+
+			rhsStr, err := gb.nodeToGoRegex(typedNode.Rhs)
+			if err != nil {
+				return "", err
+			}
+
+			return lhsStr + "|" + rhsStr, nil
+		}
+
+		lhsStr, err := gb.concatOperand(typedNode.Lhs)
 		if err != nil {
 			return "", err
 		}
 
-		rhsStr, err := gb.nodeToGoRegex(typedNode.Rhs)
+		rhsStr, err := gb.concatOperand(typedNode.Rhs)
 		if err != nil {
 			return "", err
 		}
@@ -94,65 +237,134 @@ func (gb *GoBackend) nodeToGoRegex(n Node) (out string, err error) {
 		return lhsStr + rhsStr, nil
 
 	case *FuncExpr:
-		return gb.execFunc(typedNode)
+		return gb.callFunc(typedNode), nil
 
 	case *LiteralExpr:
 		return gb.escapeString(typedNode.Value), nil
 
+	case *ObjectLiteralExpr:
+		return "", &BackendError{
+			Err: fmt.Errorf("objects (e.g. {case_sensitive: true}) can only be passed to the functions 'set_options' and 'with_options'"),
+			Pos: typedNode.StartPos(),
+			End: typedNode.EndPos(),
+		}
+
 	default:
 		return "", fmt.Errorf("unhandled node type in GoBackend.AstToGoRegex. Node=%+v", n)
 	}
 }
 
+// callFunc runs the function and records its error instead of returning it, so that the errors of the other functions of the query are found as well.
+// Functions that are incomplete because of syntax errors aren't run, but the functions inside their arguments still are
+func (gb *GoBackend) callFunc(fExpr *FuncExpr) string {
+
+	if gb.ast != nil && gb.ast.IsIncomplete(fExpr) {
+
+		for _, arg := range fExpr.Args {
+			gb.nodeToGoRegex(arg)
+		}
+
+		return ""
+	}
+
+	out, err := gb.execFunc(fExpr)
+	if err != nil {
+		gb.errs.Add(err)
+		return ""
+	}
+
+	// Quantifiers are swapped here and not in execFunc, as possessive and lazy quantifiers are made from the greedy ones execFunc produces
+	if gb.isSwappingGreediness() && slices.Contains(greedyQuantifierFuncs, fExpr.Ident.Name) {
+		out, _ = gb.getSyntax().lazy(out)
+	}
+
+	return out
+}
+
+// greedyQuantifierFuncs are the functions producing greedy quantifiers that have a lazy form. exactly isn't one of them, as '{n}' and '{n}?' match the same
+var greedyQuantifierFuncs = []string{"any_chars", "zero_plus_of", "one_plus_of", "count_between", "optional", "at_least", "at_most"}
+
+// isSwappingGreediness reports whether the ungreedy option is set for a dialect without an ungreedy flag, in which case it is applied by
+// writing greedy quantifiers as lazy ones and lazy quantifiers as greedy ones
+func (gb *GoBackend) isSwappingGreediness() bool {
+	return gb.Opts.Ungreedy && !gb.getSyntax().hasUngreedyFlag()
+}
+
 func (gb *GoBackend) execFunc(fExpr *FuncExpr) (out string, err error) {
 
 	switch fExpr.Ident.Name {
 
 	case "set_options":
 
-		// Loop over args and change state depending on each
+		// Loop over args and change state depending on each. Each wrong key is reported and then skipped, so all of them are reported at once
 		for i := 0; i < len(fExpr.Args); i++ {
 
-			switch typedArg := fExpr.Args[i].(type) {
+			typedArg, ok := fExpr.Args[i].(*ObjectLiteralExpr)
+			if !ok {
+				gb.errs.Add(argError(fExpr, fExpr.Args[i], "only one passed object (e.g. {case_sensitive:true}) is allowed as input to the function %s", fExpr.Ident.Name))
+				continue
+			}
 
-			case *ObjectLiteralExpr:
+			for i := 0; i < len(typedArg.KeyVals); i++ {
 
-				for i := 0; i < len(typedArg.KeyVals); i++ {
+				err := gb.setOption(fExpr, &typedArg.KeyVals[i], &gb.Opts)
+				if err != nil {
+					gb.errs.Add(err)
+				}
+			}
+		}
 
-					kva := &typedArg.KeyVals[i]
-					valStr, err := gb.nodeToGoRegex(kva.Val)
-					if err != nil {
-						return "", err
-					}
+	case "with_options":
 
-					switch kva.Key.Name {
+		if len(fExpr.Args) != 2 {
+			return "", argCountError(fExpr, "two arguments")
+		}
 
-					case "case_sensitive":
-						flagVal, err := gb.stringToBool(valStr)
-						if err != nil {
-							return "", fmt.Errorf("invalid value for case_sensitive. err=%s", err)
-						}
+		optsObj, ok := fExpr.Args[0].(*ObjectLiteralExpr)
+		if !ok {
+			return "", argError(fExpr, fExpr.Args[0], "first argument of function '%s' must be an object of options (e.g. {case_sensitive: true}), but found %s", fExpr.Ident.Name, describeNode(fExpr.Args[0]))
+		}
 
-						gb.Opts.CaseSensitive = flagVal
+		// Like set_options, each wrong key is reported so all of them are reported at once
+		outerOpts := gb.Opts
+		innerOpts := gb.Opts
+		hasOptErrs := false
+		for i := 0; i < len(optsObj.KeyVals); i++ {
 
-					case "find_all_matches":
-						flagVal, err := gb.stringToBool(valStr)
-						if err != nil {
-							return "", fmt.Errorf("invalid value for find_all_matches. err=%s", err)
-						}
+			kva := &optsObj.KeyVals[i]
+			optErr := gb.setOption(fExpr, kva, &innerOpts)
+			if kva.Key.Name == "find_all_matches" {
+				optErr = argError(fExpr, kva, "option 'find_all_matches' applies to the whole query, so it can only be set with the function set_options")
+			}
 
-						gb.Opts.FindAllMatches = flagVal
+			if optErr != nil {
+				gb.errs.Add(optErr)
+				hasOptErrs = true
+			}
+		}
 
-					default:
-						return "", fmt.Errorf("unknown parameter '%s' in the function %s", kva.Key.Name, fExpr.Ident.Name)
-					}
-				}
+		if hasOptErrs {
+			return "", nil
+		}
 
-			default:
-				return "", fmt.Errorf("only one passed object (e.g. {case_sensitive:true}) is allowed as input to the function %s", fExpr.Ident.Name)
-			}
+		// The expression is written with the scoped options, so that options emulated by how the regex is written (e.g. ungreedy in JavaScript) apply to it as well
+		outerSyntax := gb.syntax
+		gb.Opts = innerOpts
+		gb.syntax = gb.getSyntax().withOptions(innerOpts)
+		regexString, err := gb.nodeToGoRegex(fExpr.Args[1])
+		gb.Opts = outerOpts
+		gb.syntax = outerSyntax
+		if err != nil {
+			return "", err
+		}
+
+		flags, ok := gb.getSyntax().scopedFlags(outerOpts, innerOpts)
+		if !ok || flags == "" {
+			return gb.nonCapturingGroup(fExpr, regexString), nil
 		}
 
+		out += "(?" + flags + ":" + regexString + ")"
+
 	case "any_strings_of":
 
 		for i := 0; i < len(fExpr.Args); i++ {
@@ -169,62 +381,92 @@ func (gb *GoBackend) execFunc(fExpr *FuncExpr) (out string, err error) {
 			}
 		}
 
-	case "any_chars_of":
+	case "any_chars_of", "none_of_chars":
 
+		// An empty set would leave a quantifier after it without anything to repeat (e.g. '?' of optional(any_chars_of())),
+		// and none_of_chars() would silently match nothing instead of any character
 		if len(fExpr.Args) == 0 {
-			break
+			return "", argCountError(fExpr, "at least one argument")
 		}
 
-		out += "["
-		for i := 0; i < len(fExpr.Args); i++ {
+		cs, err := gb.argsToCharSet(fExpr)
+		if err != nil {
+			return "", err
+		}
 
-			regexString, err := gb.nodeToGoRegex(fExpr.Args[i])
-			if err != nil {
-				return "", err
-			}
+		cs.IsNegated = fExpr.Ident.Name == "none_of_chars"
 
-			out += regexString
+		charSetString, ok := gb.getSyntax().charSet(cs)
+		if !ok {
+			return "", funcError(fExpr, "the characters passed to function '%s' can't be expressed as a character set in the '%s' regex dialect", fExpr.Ident.Name, gb.getSyntax().dialect())
 		}
-		out += "]"
 
-	case "starts_with":
+		out += charSetString
+
+	case "starts_with", "line_starts_with", "text_starts_with":
 
 		if len(fExpr.Args) != 1 {
-			return "", fmt.Errorf("function '%s' must have one argument but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "one argument")
 		}
 
-		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		regexString, err := gb.concatOperand(fExpr.Args[0])
 		if err != nil {
 			return "", err
 		}
 
-		out += "^" + regexString
+		out += gb.anchor(fExpr.Ident.Name) + regexString
 
-	case "ends_with":
+	case "ends_with", "line_ends_with", "text_ends_with":
 
 		if len(fExpr.Args) != 1 {
-			return "", fmt.Errorf("function '%s' must have one argument but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "one argument")
 		}
 
-		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		regexString, err := gb.concatOperand(fExpr.Args[0])
 		if err != nil {
 			return "", err
 		}
 
-		out += regexString + "$"
+		out += regexString + gb.anchor(fExpr.Ident.Name)
 
 	case "any_chars":
 
 		if len(fExpr.Args) != 0 {
-			return "", fmt.Errorf("function '%s' must have no arguments but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "no arguments")
+		}
+
+		out += gb.getSyntax().anyChar(gb.Opts.DotMatchesNewline) + "*"
+
+	case "any_char":
+
+		if len(fExpr.Args) != 0 {
+			return "", argCountError(fExpr, "no arguments")
+		}
+
+		out += gb.getSyntax().anyChar(gb.Opts.DotMatchesNewline)
+
+	case "digit", "not_digit", "letter", "not_letter", "whitespace", "not_whitespace", "word_char", "not_word_char":
+
+		if len(fExpr.Args) != 0 {
+			return "", argCountError(fExpr, "no arguments")
+		}
+
+		isNegated := strings.HasPrefix(fExpr.Ident.Name, "not_")
+		out += gb.getSyntax().charClass(charClassFuncs[strings.TrimPrefix(fExpr.Ident.Name, "not_")], isNegated)
+
+	case "unicode_category", "unicode_script", "not_unicode":
+
+		class, err := gb.unicodeClass(fExpr)
+		if err != nil {
+			return "", err
 		}
 
-		out += ".*"
+		out += class
 
 	case "zero_plus_of":
 
 		if len(fExpr.Args) != 1 {
-			return "", fmt.Errorf("function '%s' must have one argument but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "one argument")
 		}
 
 		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
@@ -233,12 +475,25 @@ func (gb *GoBackend) execFunc(fExpr *FuncExpr) (out string, err error) {
 		}
 
 		// Use a non capturing group for performance
-		out += "(?:" + regexString + ")*"
+		out += gb.nonCapturingGroup(fExpr, regexString) + "*"
 
 	case "one_plus_of":
 
 		if len(fExpr.Args) != 1 {
-			return "", fmt.Errorf("function '%s' must have one argument but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		if err != nil {
+			return "", err
+		}
+
+		out += gb.nonCapturingGroup(fExpr, regexString) + "+"
+
+	case "capture":
+
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
 		}
 
 		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
@@ -246,12 +501,36 @@ func (gb *GoBackend) execFunc(fExpr *FuncExpr) (out string, err error) {
 			return "", err
 		}
 
-		out += "(?:" + regexString + ")+"
+		out += "(" + regexString + ")"
+
+	case "capture_as":
+
+		if len(fExpr.Args) != 2 {
+			return "", argCountError(fExpr, "two arguments")
+		}
+
+		name, err := gb.captureName(fExpr)
+		if err != nil {
+			return "", err
+		}
+
+		regexString, err := gb.nodeToGoRegex(fExpr.Args[1])
+		if err != nil {
+			return "", err
+		}
+
+		namedCapture, ok := gb.getSyntax().namedCapture(name, regexString)
+		if !ok {
+			namedCapture = "(" + regexString + ")"
+			gb.addDiagnostic(fExpr, fmt.Sprintf("the '%s' regex dialect doesn't support named capture groups, so the capture '%s' is an unnamed capture group", gb.getSyntax().dialect(), name))
+		}
+
+		out += namedCapture
 
 	case "from_to":
 
 		if len(fExpr.Args) != 2 {
-			return "", fmt.Errorf("function '%s' must have two arguments but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "two arguments")
 		}
 
 		firstParamRegexString, err := gb.nodeToGoRegex(fExpr.Args[0])
@@ -269,76 +548,1112 @@ func (gb *GoBackend) execFunc(fExpr *FuncExpr) (out string, err error) {
 	case "count_between":
 
 		if len(fExpr.Args) != 3 {
-			return "", fmt.Errorf("function '%s' must have three arguments but was passed %d arguments", fExpr.Ident.Name, len(fExpr.Args))
+			return "", argCountError(fExpr, "three arguments")
 		}
 
-		firstParamRegexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		min, err := gb.countArg(fExpr, fExpr.Args[1])
 		if err != nil {
 			return "", err
 		}
 
-		secondParamRegexString, err := gb.nodeToGoRegex(fExpr.Args[1])
+		max, err := gb.countArg(fExpr, fExpr.Args[2])
 		if err != nil {
 			return "", err
 		}
 
-		thirdParamRegexString, err := gb.nodeToGoRegex(fExpr.Args[2])
+		if min > max {
+			return "", argError(fExpr, fExpr.Args[2], "maximum count %d passed to function '%s' must not be less than the minimum count %d", max, fExpr.Ident.Name, min)
+		}
+
+		quantified, err := gb.quantify(fExpr, fExpr.Args[0], fmt.Sprintf("{%d,%d}", min, max))
 		if err != nil {
 			return "", err
 		}
 
-		out += firstParamRegexString + "{" + secondParamRegexString + "," + thirdParamRegexString + "}"
+		out += quantified
 
-	default:
-		return "", fmt.Errorf("trying to call unknown function '%s'", fExpr.Ident.Name)
-	}
+	case "optional":
 
-	return out, err
-}
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
 
-func (gb *GoBackend) stringToBool(str string) (bool, error) {
+		quantified, err := gb.quantify(fExpr, fExpr.Args[0], "?")
+		if err != nil {
+			return "", err
+		}
 
-	if str == "true" {
-		return true, nil
-	}
+		out += quantified
 
-	if str == "false" {
-		return false, nil
-	}
+	case "exactly", "at_least", "at_most":
 
-	return false, fmt.Errorf("value '%v' is not a valid boolean value, only 'true' and 'false' are allowed", str)
-}
+		if len(fExpr.Args) != 2 {
+			return "", argCountError(fExpr, "two arguments")
+		}
 
-func (gb *GoBackend) ApplyOptionsToRegexString(regexString string) string {
+		n, err := gb.countArg(fExpr, fExpr.Args[1])
+		if err != nil {
+			return "", err
+		}
 
-	flagsString := "(?"
-	if !gb.Opts.CaseSensitive {
-		flagsString += "i"
-	}
+		quantifier := fmt.Sprintf("{%d}", n)
+		if fExpr.Ident.Name == "at_least" {
+			quantifier = fmt.Sprintf("{%d,}", n)
+		} else if fExpr.Ident.Name == "at_most" {
+			quantifier = fmt.Sprintf("{0,%d}", n)
+		}
 
-	// In Go regex, 'g' flag doesn't exist, rather finding one or many is controlled by the regex.Regexp function used.
-	// For example, for Go regex '(?i)case', Regexp.FindString("casecase") returns 'case',
-	// while Regexp.FindAllString("casecase") returns ["case", "case"].
-	//
-	// if gb.Opts.FindAllMatches {
-	// 	flagsString += "g"
-	// }
+		quantified, err := gb.quantify(fExpr, fExpr.Args[0], quantifier)
+		if err != nil {
+			return "", err
+		}
 
-	return flagsString + ")" + regexString
-}
+		out += quantified
 
-func (gb *GoBackend) escapeString(original string) string {
+	case "followed_by", "not_followed_by", "preceded_by", "not_preceded_by":
 
-	sb := strings.Builder{}
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
 
-	for _, r := range original {
+		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		if err != nil {
+			return "", err
+		}
 
-		if r == '.' || r == '(' || r == ')' || r == '[' || r == ']' || r == '{' || r == '}' || r == '\\' {
-			sb.WriteRune('\\')
+		isNegated := strings.HasPrefix(fExpr.Ident.Name, "not_")
+		lookaround, ok := "", false
+		if strings.HasSuffix(fExpr.Ident.Name, "followed_by") {
+			lookaround, ok = gb.getSyntax().lookahead(regexString, isNegated)
+		} else {
+			lookaround, ok = gb.getSyntax().lookbehind(regexString, isNegated)
 		}
 
-		sb.WriteRune(r)
-	}
+		if !ok {
+			return "", gb.unsupportedFuncError(fExpr, "lookarounds")
+		}
 
-	return sb.String()
+		if strings.HasSuffix(fExpr.Ident.Name, "preceded_by") {
+
+			err = gb.checkLookbehindWidth(fExpr)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		out += lookaround
+
+	case "same_as_capture":
+
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		nameLit, ok := fExpr.Args[0].(*LiteralExpr)
+		if !ok || nameLit.Type != TokenType_String {
+			return "", argError(fExpr, fExpr.Args[0], "argument of function '%s' must be a string literal holding the capture name, but found %s", fExpr.Ident.Name, describeNode(fExpr.Args[0]))
+		}
+
+		if !slices.Contains(gb.captureNames, nameLit.Value) {
+			return "", argError(fExpr, nameLit, "capture name '%s' passed to function '%s' must be the name of a capture_as that comes before it", nameLit.Value, fExpr.Ident.Name)
+		}
+
+		backreference, ok := gb.getSyntax().backreference(nameLit.Value)
+		if !ok {
+			return "", gb.unsupportedFuncError(fExpr, "backreferences")
+		}
+
+		out += backreference
+
+	case "atomic":
+
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		regexString, err := gb.nodeToGoRegex(fExpr.Args[0])
+		if err != nil {
+			return "", err
+		}
+
+		atomicGroup, ok := gb.getSyntax().atomicGroup(regexString)
+		if !ok {
+			return "", gb.unsupportedFuncError(fExpr, "atomic groups")
+		}
+
+		out += atomicGroup
+
+	case "possessive_zero_plus_of", "possessive_one_plus_of", "possessive_count_between":
+
+		if fExpr.Ident.Name == "possessive_count_between" && len(fExpr.Args) != 3 {
+			return "", argCountError(fExpr, "three arguments")
+		}
+
+		if fExpr.Ident.Name != "possessive_count_between" && len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		// Possessive quantifiers are the greedy ones with a '+' after them, so we produce the greedy one then make it possessive
+		greedyFExpr := *fExpr
+		greedyFExpr.Ident.Name = strings.TrimPrefix(fExpr.Ident.Name, "possessive_")
+		regexString, err := gb.execFunc(&greedyFExpr)
+		if err != nil {
+			return "", err
+		}
+
+		possessive, ok := gb.getSyntax().possessive(regexString)
+		if !ok {
+			return "", gb.unsupportedFuncError(fExpr, "possessive quantifiers")
+		}
+
+		out += possessive
+
+	case "lazy_zero_plus_of", "lazy_one_plus_of", "lazy_count_between":
+
+		if fExpr.Ident.Name == "lazy_count_between" && len(fExpr.Args) != 3 {
+			return "", argCountError(fExpr, "three arguments")
+		}
+
+		if fExpr.Ident.Name != "lazy_count_between" && len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		// Like possessive quantifiers, lazy quantifiers are the greedy ones with a '?' after them
+		greedyFExpr := *fExpr
+		greedyFExpr.Ident.Name = strings.TrimPrefix(fExpr.Ident.Name, "lazy_")
+		regexString, err := gb.execFunc(&greedyFExpr)
+		if err != nil {
+			return "", err
+		}
+
+		// When the ungreedy option is applied by swapping quantifiers, lazy quantifiers are written as greedy ones
+		if gb.isSwappingGreediness() {
+			out += regexString
+			break
+		}
+
+		lazy, ok := gb.getSyntax().lazy(regexString)
+		if !ok {
+			return "", gb.unsupportedFuncError(fExpr, "lazy quantifiers")
+		}
+
+		out += lazy
+
+	default:
+		return "", funcError(fExpr, "trying to call unknown function '%s'", fExpr.Ident.Name)
+	}
+
+	return out, err
+}
+
+// maxRepeatCount is the largest count a quantifier like count_between accepts, which is the limit of RE2 (and so of Go regex)
+const maxRepeatCount = 1000
+
+// countArg returns the count held by an argument of a quantifier function like exactly, which must be an integer literal between 0 and maxRepeatCount
+func (gb *GoBackend) countArg(fExpr *FuncExpr, arg Expr) (int, error) {
+
+	lit, ok := arg.(*LiteralExpr)
+	if !ok || lit.Type != TokenType_Int {
+		return 0, argError(fExpr, arg, "counts passed to function '%s' must be integer literals (e.g. 10), but found %s", fExpr.Ident.Name, describeNode(arg))
+	}
+
+	count, err := strconv.Atoi(lit.Value)
+	if err != nil || count < 0 || count > maxRepeatCount {
+		return 0, argError(fExpr, arg, "count %s passed to function '%s' must be between 0 and %d", lit.Value, fExpr.Ident.Name, maxRepeatCount)
+	}
+
+	return count, nil
+}
+
+// quantify applies quantifier (e.g. '?' or '{2,5}') to the regex of arg. The regex is put in a non-capturing group first,
+// unless it is a single character, set or group that the quantifier already applies to as a whole
+func (gb *GoBackend) quantify(fExpr *FuncExpr, arg Expr, quantifier string) (string, error) {
+
+	regexString, err := gb.nodeToGoRegex(arg)
+	if err != nil {
+		return "", err
+	}
+
+	if !isQuantifiable(arg) {
+		regexString = gb.nonCapturingGroup(fExpr, regexString)
+	}
+
+	return regexString + quantifier, nil
+}
+
+// isQuantifiable reports whether the regex of n is a single unit (e.g. 'a', '[a-z]' or '(ab)') that can be quantified without grouping it
+func isQuantifiable(n Expr) bool {
+
+	switch typedNode := n.(type) {
+
+	case *LiteralExpr:
+		return utf8.RuneCountInString(typedNode.Value) == 1
+
+	case *FuncExpr:
+
+		switch typedNode.Ident.Name {
+		case "any_chars_of", "none_of_chars", "any_char", "unicode_category", "unicode_script", "not_unicode", "capture", "capture_as", "with_options":
+			return true
+		}
+
+		_, isClass := charClassFuncs[strings.TrimPrefix(typedNode.Ident.Name, "not_")]
+		return isClass
+	}
+
+	return false
+}
+
+// checkLookbehindWidth returns an error if the text matched by the argument of the lookbehind function (e.g. preceded_by) doesn't have the length
+// the dialect requires, as regex engines reject such lookbehinds only when the regex is compiled
+func (gb *GoBackend) checkLookbehindWidth(fExpr *FuncExpr) error {
+
+	isFixedWidth, maxWidth := gb.getSyntax().lookbehindLimits()
+	minLen, maxLen := patternWidth(fExpr.Args[0])
+
+	if isFixedWidth && minLen != maxLen {
+		return argError(fExpr, fExpr.Args[0], "pattern passed to function '%s' must always match the same number of characters with the '%s' regex dialect, but it can match %s characters",
+			fExpr.Ident.Name, gb.getSyntax().dialect(), describeWidth(minLen, maxLen))
+	}
+
+	if maxWidth > 0 && minLen != maxLen && (maxLen == -1 || maxLen > maxWidth) {
+		return argError(fExpr, fExpr.Args[0], "pattern passed to function '%s' must match at most %d characters with the '%s' regex dialect, but it can match %s characters",
+			fExpr.Ident.Name, maxWidth, gb.getSyntax().dialect(), describeWidth(minLen, maxLen))
+	}
+
+	return nil
+}
+
+// patternWidth returns the smallest and largest number of characters the regex of the pattern matches, where a maxLen of -1 means there is no limit.
+// Zero width functions like starts_with and the lookarounds only count the characters of the pattern passed to them
+func patternWidth(e Expr) (minLen, maxLen int) {
+
+	switch typedNode := e.(type) {
+
+	case *LiteralExpr:
+		l := utf8.RuneCountInString(typedNode.Value)
+		return l, l
+
+	case *BinaryExpr:
+
+		lhsMin, lhsMax := patternWidth(typedNode.Lhs)
+		rhsMin, rhsMax := patternWidth(typedNode.Rhs)
+		if typedNode.Type == TokenType_Or {
+			return min(lhsMin, rhsMin), largerWidth(lhsMax, rhsMax)
+		}
+
+		return lhsMin + rhsMin, addWidth(lhsMax, rhsMax)
+
+	case *FuncExpr:
+
+		args := typedNode.Args
+		name := strings.TrimPrefix(strings.TrimPrefix(typedNode.Ident.Name, "lazy_"), "possessive_")
+		switch name {
+
+		case "any_chars_of", "none_of_chars", "any_char", "unicode_category", "unicode_script", "not_unicode":
+			return 1, 1
+
+		case "followed_by", "not_followed_by", "preceded_by", "not_preceded_by":
+			return 0, 0
+
+		case "any_strings_of":
+
+			if len(args) == 0 {
+				return 0, 0
+			}
+
+			minLen, maxLen = patternWidth(args[0])
+			for _, arg := range args[1:] {
+
+				argMin, argMax := patternWidth(arg)
+				minLen, maxLen = min(minLen, argMin), largerWidth(maxLen, argMax)
+			}
+
+			return minLen, maxLen
+
+		case "capture", "atomic", "starts_with", "ends_with", "line_starts_with", "line_ends_with", "text_starts_with", "text_ends_with":
+
+			if len(args) == 1 {
+				return patternWidth(args[0])
+			}
+
+		case "capture_as", "with_options":
+
+			if len(args) == 2 {
+				return patternWidth(args[1])
+			}
+
+		case "any_chars":
+			return 0, -1
+
+		case "zero_plus_of", "one_plus_of", "optional", "count_between", "exactly", "at_least", "at_most":
+			return quantifiedWidth(name, args)
+		}
+
+		if _, isClass := charClassFuncs[strings.TrimPrefix(name, "not_")]; isClass {
+			return 1, 1
+		}
+	}
+
+	// Unknown widths (e.g. of same_as_capture) have no limit
+	return 0, -1
+}
+
+// quantifiedWidth returns the width of the quantifier function (e.g. count_between) applied to its arguments, like patternWidth does
+func quantifiedWidth(funcName string, args []Expr) (minLen, maxLen int) {
+
+	if len(args) == 0 {
+		return 0, -1
+	}
+
+	argMin, argMax := patternWidth(args[0])
+	counts := make([]int, 0, 2)
+	for _, arg := range args[1:] {
+
+		lit, ok := arg.(*LiteralExpr)
+		if !ok {
+			return 0, -1
+		}
+
+		count, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return 0, -1
+		}
+
+		counts = append(counts, count)
+	}
+
+	// The counts of each function, where -1 is no limit
+	minCount, maxCount := 0, -1
+	switch {
+	case funcName == "one_plus_of":
+		minCount = 1
+	case funcName == "optional":
+		maxCount = 1
+	case funcName == "count_between" && len(counts) == 2:
+		minCount, maxCount = counts[0], counts[1]
+	case funcName == "exactly" && len(counts) == 1:
+		minCount, maxCount = counts[0], counts[0]
+	case funcName == "at_least" && len(counts) == 1:
+		minCount = counts[0]
+	case funcName == "at_most" && len(counts) == 1:
+		maxCount = counts[0]
+	}
+
+	maxLen = -1
+	if (maxCount != -1 && argMax != -1) || maxCount == 0 || argMax == 0 {
+		maxLen = max(maxCount, 0) * max(argMax, 0)
+	}
+
+	return minCount * argMin, maxLen
+}
+
+// addWidth adds two widths of patternWidth, where -1 is no limit
+func addWidth(a, b int) int {
+
+	if a == -1 || b == -1 {
+		return -1
+	}
+
+	return a + b
+}
+
+// largerWidth returns the larger of two widths of patternWidth, where -1 is no limit
+func largerWidth(a, b int) int {
+
+	if a == -1 || b == -1 {
+		return -1
+	}
+
+	return max(a, b)
+}
+
+// describeWidth describes the widths returned by patternWidth for error messages (e.g. '2', '1 to 3' or '1 or more')
+func describeWidth(minLen, maxLen int) string {
+
+	if maxLen == -1 {
+		return fmt.Sprintf("%d or more", minLen)
+	}
+
+	if minLen == maxLen {
+		return fmt.Sprint(minLen)
+	}
+
+	return fmt.Sprintf("%d to %d", minLen, maxLen)
+}
+
+// anchor returns the anchor written by one of the starts_with and ends_with functions. starts_with and ends_with follow the multiline option,
+// while the line_ and text_ functions always match at lines and at the whole text respectively
+func (gb *GoBackend) anchor(funcName string) string {
+
+	isStart := strings.HasSuffix(funcName, "starts_with")
+	switch {
+	case strings.HasPrefix(funcName, "line_"):
+		return gb.getSyntax().lineAnchor(isStart, gb.Opts.Multiline)
+	case strings.HasPrefix(funcName, "text_"):
+		return gb.getSyntax().textAnchor(isStart)
+	case isStart:
+		return "^"
+	case gb.Opts.Multiline:
+		return "$"
+	default:
+		return gb.getSyntax().endAnchor()
+	}
+}
+
+// concatOperand returns the regex of e for concatenating it with other regexes. Alternations (e.g. 'a|b') are put in a group,
+// as otherwise the alternation would take in the regexes next to it
+func (gb *GoBackend) concatOperand(e Expr) (string, error) {
+
+	regexString, err := gb.nodeToGoRegex(e)
+	if err != nil || regexString == "" || !isAlternation(e) {
+		return regexString, err
+	}
+
+	group, ok := gb.getSyntax().nonCapturingGroup(regexString)
+	if !ok {
+		group = "(" + regexString + ")"
+		gb.addDiagnostic(e, fmt.Sprintf("the '%s' regex dialect doesn't support non-capturing groups, so an alternation joined with other expressions is put in a capture group", gb.getSyntax().dialect()))
+	}
+
+	return group, nil
+}
+
+// isAlternation reports whether the regex of e is an alternation at its top level (e.g. 'a|b', but not '(a|b)')
+func isAlternation(e Expr) bool {
+
+	switch typedNode := e.(type) {
+
+	case *BinaryExpr:
+		return typedNode.Type == TokenType_Or
+
+	case *FuncExpr:
+
+		if typedNode.Ident.Name != "any_strings_of" {
+			return false
+		}
+
+		return len(typedNode.Args) > 1 || (len(typedNode.Args) == 1 && isAlternation(typedNode.Args[0]))
+	}
+
+	return false
+}
+
+// unsupportedFuncError returns an error saying that the function can't be used because the dialect of this backend doesn't support the passed feature
+func (gb *GoBackend) unsupportedFuncError(fExpr *FuncExpr, feature string) error {
+	return funcError(fExpr, "function '%s' can't be used with the '%s' regex dialect because it doesn't support %s", fExpr.Ident.Name, gb.getSyntax().dialect(), feature)
+}
+
+// nonCapturingGroup groups regexString without capturing it. If the dialect doesn't support non-capturing groups a capturing group is used,
+// which matches the same text but changes the numbers of the capture groups after it
+func (gb *GoBackend) nonCapturingGroup(fExpr *FuncExpr, regexString string) string {
+
+	group, ok := gb.getSyntax().nonCapturingGroup(regexString)
+	if ok {
+		return group
+	}
+
+	gb.addDiagnostic(fExpr, fmt.Sprintf("the '%s' regex dialect doesn't support non-capturing groups, so function '%s' adds a capture group", gb.getSyntax().dialect(), fExpr.Ident.Name))
+	return "(" + regexString + ")"
+}
+
+func (gb *GoBackend) addDiagnostic(n Node, msg string) {
+	gb.diags = append(gb.diags, Diagnostic{
+		Pos: n.StartPos(),
+		Msg: msg,
+	})
+}
+
+// argsToCharSet creates a character set from the arguments of a function like any_chars_of, where each argument is either a literal whose characters are all added to the set,
+// a from_to call which adds a range of characters, or a character class.
+//
+// Any other argument is an error, as its regex (e.g. '(?:a)+' of one_plus_of('a')) would be read as single characters when put inside '[...]'
+// and match something else than written
+func (gb *GoBackend) argsToCharSet(fExpr *FuncExpr) (*charSet, error) {
+
+	cs := &charSet{
+		Items: make([]charSetItem, 0, len(fExpr.Args)),
+	}
+
+	for i := 0; i < len(fExpr.Args); i++ {
+
+		switch typedArg := fExpr.Args[i].(type) {
+
+		case *LiteralExpr:
+
+			for _, r := range typedArg.Value {
+				cs.Items = append(cs.Items, charSetItem{From: r, To: r})
+			}
+
+		case *FuncExpr:
+
+			if typedArg.Ident.Name == "unicode_category" || typedArg.Ident.Name == "unicode_script" || typedArg.Ident.Name == "not_unicode" {
+
+				class, err := gb.unicodeClass(typedArg)
+				if err != nil {
+					return nil, err
+				}
+
+				cs.Items = append(cs.Items, charSetItem{Class: class})
+				continue
+			}
+
+			if class, ok := charClassFuncs[strings.TrimPrefix(typedArg.Ident.Name, "not_")]; ok {
+
+				if len(typedArg.Args) != 0 {
+					return nil, argCountError(typedArg, "no arguments")
+				}
+
+				classInSet, ok := charClassInSet(gb.getSyntax().charClass(class, strings.HasPrefix(typedArg.Ident.Name, "not_")))
+				if !ok {
+					return nil, funcError(typedArg, "function '%s' can't be passed to function '%s' with the '%s' regex dialect, as the dialect can't express it inside a character set", typedArg.Ident.Name, fExpr.Ident.Name, gb.getSyntax().dialect())
+				}
+
+				cs.Items = append(cs.Items, charSetItem{Class: classInSet})
+				continue
+			}
+
+			if typedArg.Ident.Name != "from_to" {
+				return nil, argError(fExpr, typedArg, "function '%s' can only be passed literals (e.g. 'abc'), from_to calls and character classes, but was passed a call to '%s'", fExpr.Ident.Name, typedArg.Ident.Name)
+			}
+
+			if len(typedArg.Args) != 2 {
+				return nil, argCountError(typedArg, "two arguments")
+			}
+
+			from, err := gb.charRangeEnd(typedArg, typedArg.Args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			to, err := gb.charRangeEnd(typedArg, typedArg.Args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			if from > to {
+				return nil, funcError(typedArg, "the first argument of function '%s' must not come after the second argument, but found '%c' and '%c'", typedArg.Ident.Name, from, to)
+			}
+
+			cs.Items = append(cs.Items, charSetItem{From: from, To: to})
+
+		default:
+			return nil, argError(fExpr, typedArg, "function '%s' can only be passed literals (e.g. 'abc'), from_to calls and character classes, but was passed %s", fExpr.Ident.Name, describeNode(typedArg))
+		}
+	}
+
+	return cs, nil
+}
+
+// unicodeClass validates a call to unicode_category, unicode_script or not_unicode and returns it written for the dialect (e.g. '\p{Lu}').
+// Names are validated against the tables of Go's unicode package
+func (gb *GoBackend) unicodeClass(fExpr *FuncExpr) (string, error) {
+
+	classExpr := fExpr
+	isNegated := fExpr.Ident.Name == "not_unicode"
+	if isNegated {
+
+		if len(fExpr.Args) != 1 {
+			return "", argCountError(fExpr, "one argument")
+		}
+
+		innerExpr, ok := fExpr.Args[0].(*FuncExpr)
+		if !ok || (innerExpr.Ident.Name != "unicode_category" && innerExpr.Ident.Name != "unicode_script") {
+			return "", argError(fExpr, fExpr.Args[0], "argument of function '%s' must be a call to unicode_category or unicode_script, but found %s", fExpr.Ident.Name, describeNode(fExpr.Args[0]))
+		}
+
+		classExpr = innerExpr
+	}
+
+	isScript := classExpr.Ident.Name == "unicode_script"
+	kind, example, table := "category", "Lu", unicode.Categories
+	if isScript {
+		kind, example, table = "script", "Greek", unicode.Scripts
+	}
+
+	if len(classExpr.Args) != 1 {
+		return "", argCountError(classExpr, "one argument")
+	}
+
+	nameLit, ok := classExpr.Args[0].(*LiteralExpr)
+	if !ok || nameLit.Type != TokenType_String {
+		return "", argError(classExpr, classExpr.Args[0], "argument of function '%s' must be a string literal holding the name of a unicode %s (e.g. '%s'), but found %s", classExpr.Ident.Name, kind, example, describeNode(classExpr.Args[0]))
+	}
+
+	if _, ok := table[nameLit.Value]; !ok {
+
+		for name := range table {
+			if strings.EqualFold(name, nameLit.Value) {
+				return "", argError(classExpr, nameLit, "'%s' passed to function '%s' is not a unicode %s. Did you mean '%s'?", nameLit.Value, classExpr.Ident.Name, kind, name)
+			}
+		}
+
+		return "", argError(classExpr, nameLit, "'%s' passed to function '%s' is not a unicode %s (e.g. '%s')", nameLit.Value, classExpr.Ident.Name, kind, example)
+	}
+
+	class, ok := gb.getSyntax().unicodeClass(nameLit.Value, isScript, isNegated)
+	if !ok {
+		return "", gb.unsupportedFuncError(fExpr, "unicode categories and scripts")
+	}
+
+	return class, nil
+}
+
+// charRangeEnd returns the single character held by an argument of a function like from_to
+func (gb *GoBackend) charRangeEnd(fExpr *FuncExpr, arg Expr) (rune, error) {
+
+	lit, ok := arg.(*LiteralExpr)
+	if !ok || utf8.RuneCountInString(lit.Value) != 1 {
+		return 0, argError(fExpr, arg, "arguments of function '%s' must be literals of exactly one character (e.g. 'a' or 0), but found %s", fExpr.Ident.Name, describeNode(arg))
+	}
+
+	r, _ := utf8.DecodeRuneInString(lit.Value)
+	return r, nil
+}
+
+// captureName validates and returns the name passed as the first argument of capture_as.
+// Names must be string literals that are valid identifiers (e.g. 'year' or 'first_name'), and must be unique within a query
+func (gb *GoBackend) captureName(fExpr *FuncExpr) (string, error) {
+
+	nameLit, ok := fExpr.Args[0].(*LiteralExpr)
+	if !ok || nameLit.Type != TokenType_String {
+		return "", argError(fExpr, fExpr.Args[0], "first argument of function '%s' must be a string literal holding the capture name, but found %s", fExpr.Ident.Name, describeNode(fExpr.Args[0]))
+	}
+
+	name := nameLit.Value
+	if !isValidIdentifier(name) {
+		return "", argError(fExpr, nameLit, "capture name '%s' passed to function '%s' is invalid. Names must start with a letter or '_' and contain only letters, digits and '_'", name, fExpr.Ident.Name)
+	}
+
+	if slices.Contains(gb.captureNames, name) {
+		return "", argError(fExpr, nameLit, "capture name '%s' passed to function '%s' is already used by another capture. Capture names must be unique", name, fExpr.Ident.Name)
+	}
+
+	gb.captureNames = append(gb.captureNames, name)
+	return name, nil
+}
+
+func isValidIdentifier(s string) bool {
+
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		if isLetter || (isDigit && i > 0) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// setOption changes opts according to one key-value pair of the options object passed to fExpr (e.g. set_options)
+func (gb *GoBackend) setOption(fExpr *FuncExpr, kva *KeyValExpr, opts *RegexOptions) error {
+
+	// Values are used as-is instead of going through nodeToGoRegex, as they are settings and not part of the regex.
+	// Anything but a literal is an error, as the regex of an expression (e.g. '.' of any_char()) is never a valid setting
+	valLit, ok := kva.Val.(*LiteralExpr)
+	if !ok {
+		return argError(fExpr, kva, "value of parameter '%s' in the function %s must be a literal (e.g. true), but found %s", kva.Key.Name, fExpr.Ident.Name, describeNode(kva.Val))
+	}
+
+	valStr := valLit.Value
+
+	switch kva.Key.Name {
+
+	case "case_sensitive":
+		flagVal, err := gb.stringToBool(valStr)
+		if err != nil {
+			return argError(fExpr, kva, "invalid value for case_sensitive. err=%s", err)
+		}
+
+		opts.CaseSensitive = flagVal
+
+	case "find_all_matches":
+		flagVal, err := gb.stringToBool(valStr)
+		if err != nil {
+			return argError(fExpr, kva, "invalid value for find_all_matches. err=%s", err)
+		}
+
+		opts.FindAllMatches = flagVal
+
+	case "ungreedy":
+		flagVal, err := gb.stringToBool(valStr)
+		if err != nil {
+			return argError(fExpr, kva, "invalid value for ungreedy. err=%s", err)
+		}
+
+		if _, ok := gb.getSyntax().lazy(""); flagVal && !ok {
+			return argError(fExpr, kva, "option 'ungreedy' can't be used with the '%s' regex dialect because it doesn't support lazy quantifiers", gb.getSyntax().dialect())
+		}
+
+		opts.Ungreedy = flagVal
+
+	case "multiline", "dot_matches_newline":
+		flagVal, err := gb.stringToBool(valStr)
+		if err != nil {
+			return argError(fExpr, kva, "invalid value for %s. err=%s", kva.Key.Name, err)
+		}
+
+		if flagVal && !gb.getSyntax().hasLineFlags() {
+			return argError(fExpr, kva, "option '%s' can't be used with the '%s' regex dialect because it doesn't have flags", kva.Key.Name, gb.getSyntax().dialect())
+		}
+
+		if kva.Key.Name == "multiline" {
+			opts.Multiline = flagVal
+		} else {
+			opts.DotMatchesNewline = flagVal
+		}
+
+	default:
+		return argError(fExpr, kva, "unknown parameter '%s' in the function %s", kva.Key.Name, fExpr.Ident.Name)
+	}
+
+	return nil
+}
+
+func (gb *GoBackend) stringToBool(str string) (bool, error) {
+
+	if str == "true" {
+		return true, nil
+	}
+
+	if str == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("value '%v' is not a valid boolean value, only 'true' and 'false' are allowed", str)
+}
+
+func (gb *GoBackend) ApplyOptionsToRegexString(regexString string) string {
+
+	flagsString := "(?"
+	if !gb.Opts.CaseSensitive {
+		flagsString += "i"
+	}
+
+	if gb.Opts.Multiline {
+		flagsString += "m"
+	}
+
+	if gb.Opts.DotMatchesNewline {
+		flagsString += "s"
+	}
+
+	if gb.Opts.Ungreedy {
+		flagsString += "U"
+	}
+
+	// In Go regex, 'g' flag doesn't exist, rather finding one or many is controlled by the regex.Regexp function used.
+	// For example, for Go regex '(?i)case', Regexp.FindString("casecase") returns 'case',
+	// while Regexp.FindAllString("casecase") returns ["case", "case"].
+	//
+	// if gb.Opts.FindAllMatches {
+	// 	flagsString += "g"
+	// }
+
+	return flagsString + ")" + regexString
+}
+
+func (gb *GoBackend) escapeString(original string) string {
+	return gb.getSyntax().escapeString(original)
+}
+
+func (gb *GoBackend) getSyntax() regexSyntax {
+
+	if gb.syntax == nil {
+		return goSyntax{}
+	}
+
+	return gb.syntax
+}
+
+// charSet is a set of characters (e.g. the ones passed to any_chars_of), which each regexSyntax writes in its own way
+type charSet struct {
+	// Items are in the order they were passed in the query
+	Items []charSetItem
+	// IsNegated means the set matches any character not in Items, like '[^abc]'
+	IsNegated bool
+}
+
+// charClassInSet returns a character class written by regexSyntax.charClass (e.g. '[[:alpha:]]') in the form used inside a character set (e.g. '[:alpha:]').
+// False is returned for classes that are negated character sets (e.g. '[^0-9]'), as they can't be put inside another set
+func charClassInSet(class string) (string, bool) {
+
+	if !strings.HasPrefix(class, "[") {
+		return class, true
+	}
+
+	if strings.HasPrefix(class, "[^") {
+		return "", false
+	}
+
+	return class[1 : len(class)-1], true
+}
+
+// charSetItem is either a range of characters from From to To (inclusive), a single character if From == To,
+// or a character class if Class is set
+type charSetItem struct {
+	From rune
+	To   rune
+	// Class is a character class as written inside a set for the dialect (e.g. '\p{Lu}' or '[:alpha:]')
+	Class string
+}
+
+func (csi charSetItem) IsRange() bool {
+	return csi.From != csi.To
+}
+
+// write writes the character set as '[...]' (or '[^...]' if negated) with items in their original order, where the characters in specialChars are escaped with a backslash
+func (cs *charSet) write(specialChars string) string {
+
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	if cs.IsNegated {
+		sb.WriteRune('^')
+	}
+
+	for _, item := range cs.Items {
+
+		if item.Class != "" {
+			sb.WriteString(item.Class)
+			continue
+		}
+
+		sb.WriteString(escapeChars(string(item.From), specialChars))
+		if item.IsRange() {
+			sb.WriteString("-" + escapeChars(string(item.To), specialChars))
+		}
+	}
+
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+// charClass is a predefined set of ASCII characters, which each regexSyntax writes in its own way so that
+// a class matches the same characters in all dialects (e.g. '\d' matches all unicode digits in Python)
+type charClass int
+
+const (
+	// charClass_Digit is '[0-9]'
+	charClass_Digit charClass = iota
+	// charClass_Letter is '[A-Za-z]'
+	charClass_Letter
+	// charClass_Whitespace is '[\t\n\f\r ]', which is what '\s' matches in Go
+	charClass_Whitespace
+	// charClass_WordChar is '[0-9A-Za-z_]'
+	charClass_WordChar
+)
+
+// charClassFuncs maps the functions matching a charClass (without the 'not_' prefix of their negations) to their class
+var charClassFuncs = map[string]charClass{
+	"digit":      charClass_Digit,
+	"letter":     charClass_Letter,
+	"whitespace": charClass_Whitespace,
+	"word_char":  charClass_WordChar,
+}
+
+// multilineAnchor is '^' or '$' with the multiline flag set just for them if it isn't set for the whole regex, for dialects supporting '(?m:...)'
+func multilineAnchor(isStart, isMultiline bool) string {
+
+	anchor := "$"
+	if isStart {
+		anchor = "^"
+	}
+
+	if isMultiline {
+		return anchor
+	}
+
+	return "(?m:" + anchor + ")"
+}
+
+// writeScopedFlags returns the flags that change the options from outer to inner, with the flags turned on before the '-' and the flags turned off after it (e.g. 'i-s').
+// The 'U' flag is only used if the dialect has it, as otherwise the ungreedy option is applied by how quantifiers are written
+func writeScopedFlags(outer, inner RegexOptions, hasUngreedyFlag bool) string {
+
+	on, off := "", ""
+	addFlag := func(flag string, isOuterSet, isInnerSet bool) {
+
+		if isOuterSet == isInnerSet {
+			return
+		}
+
+		if isInnerSet {
+			on += flag
+		} else {
+			off += flag
+		}
+	}
+
+	addFlag("i", !outer.CaseSensitive, !inner.CaseSensitive)
+	addFlag("m", outer.Multiline, inner.Multiline)
+	addFlag("s", outer.DotMatchesNewline, inner.DotMatchesNewline)
+	if hasUngreedyFlag {
+		addFlag("U", outer.Ungreedy, inner.Ungreedy)
+	}
+
+	if off == "" {
+		return on
+	}
+
+	return on + "-" + off
+}
+
+// goSyntax is the regexSyntax of Go regex
+type goSyntax struct{}
+
+var _ regexSyntax = goSyntax{}
+
+func (goSyntax) dialect() string {
+	return Dialect_Go
+}
+
+// escapeChars puts a backslash before each character of original that is in specialChars
+func escapeChars(original, specialChars string) string {
+
+	sb := strings.Builder{}
+
+	for _, r := range original {
+
+		if strings.ContainsRune(specialChars, r) {
+			sb.WriteRune('\\')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+const (
+	// goSpecialChars are the characters with a special meaning in a Go regex pattern, which are the ones escaped by regexp.QuoteMeta
+	goSpecialChars = `\.+*?()|[]{}^$`
+	// goCharSetSpecialChars are the characters with a special meaning inside a Go character set, where '[' can start a class like '[:alpha:]'
+	goCharSetSpecialChars = `\[]^-`
+)
+
+func (goSyntax) escapeString(original string) string {
+	return escapeChars(original, goSpecialChars)
+}
+
+func (goSyntax) anyChar(dotMatchesNewline bool) string {
+	return "."
+}
+
+func (goSyntax) endAnchor() string {
+	return "$"
+}
+
+func (goSyntax) lineAnchor(isStart, isMultiline bool) string {
+	return multilineAnchor(isStart, isMultiline)
+}
+
+func (goSyntax) textAnchor(isStart bool) string {
+
+	if isStart {
+		return `\A`
+	}
+
+	return `\z`
+}
+
+func (goSyntax) hasLineFlags() bool {
+	return true
+}
+
+func (goSyntax) namedCapture(name, regexString string) (string, bool) {
+	return "(?P<" + name + ">" + regexString + ")", true
+}
+
+func (goSyntax) nonCapturingGroup(regexString string) (string, bool) {
+	return "(?:" + regexString + ")", true
+}
+
+func (goSyntax) charSet(cs *charSet) (string, bool) {
+	return cs.write(goCharSetSpecialChars), true
+}
+
+func (goSyntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`\d`, `\D`},
+		charClass_Letter:     {`[[:alpha:]]`, `[[:^alpha:]]`},
+		charClass_Whitespace: {`\s`, `\S`},
+		charClass_WordChar:   {`\w`, `\W`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+func (goSyntax) unicodeClass(name string, isScript, isNegated bool) (string, bool) {
+	return writeUnicodeClass(name, isNegated), true
+}
+
+// writeUnicodeClass writes the class as '\p{name}', or '\P{name}' if negated
+func writeUnicodeClass(name string, isNegated bool) string {
+
+	if isNegated {
+		return `\P{` + name + `}`
+	}
+
+	return `\p{` + name + `}`
+}
+
+// pickCharClass returns the second string of the class if negated, otherwise the first
+func pickCharClass(class [2]string, isNegated bool) string {
+
+	if isNegated {
+		return class[1]
+	}
+
+	return class[0]
+}
+
+// RE2, which Go regex is based on, guarantees linear time matching and so doesn't support constructs that need backtracking like lookarounds and backreferences.
+// See: https://github.com/google/re2/wiki/Syntax
+
+func (goSyntax) lookahead(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (goSyntax) lookbehind(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (goSyntax) lookbehindLimits() (isFixedWidth bool, maxWidth int) {
+	return false, 0
+}
+
+func (goSyntax) backreference(name string) (string, bool) {
+	return "", false
+}
+
+func (goSyntax) atomicGroup(regexString string) (string, bool) {
+	return "", false
+}
+
+func (goSyntax) possessive(quantifiedRegexString string) (string, bool) {
+	return "", false
+}
+
+func (goSyntax) lazy(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "?", true
+}
+
+func (goSyntax) hasUngreedyFlag() bool {
+	return true
+}
+
+func (goSyntax) scopedFlags(outer, inner RegexOptions) (string, bool) {
+	return writeScopedFlags(outer, inner, true), true
+}
+
+func (gs goSyntax) withOptions(opts RegexOptions) regexSyntax {
+	return gs
 }
@@ -0,0 +1,228 @@
+package regexl
+
+// JsBackend produces ECMAScript (JavaScript) regex literals like '/^hello/iu', based on the rules here: https://tc39.es/ecma262/#sec-patterns
+//
+// Queries are validated by GoBackend as well, so JsBackend accepts exactly the queries GoBackend accepts.
+// Produced regexes always use the 'u' flag so that, like Go, they match by code points instead of UTF-16 code units.
+type JsBackend struct {
+	Opts RegexOptions
+}
+
+const Dialect_JavaScript = "javascript"
+
+func init() {
+	RegisterBackend(Dialect_JavaScript, func() Backend { return &JsBackend{} })
+}
+
+var _ Backend = &JsBackend{}
+
+func (jb *JsBackend) Dialect() string {
+	return Dialect_JavaScript
+}
+
+// AstToRegexString produces a JavaScript regex literal (e.g. /^hello/iu) from the AST by calling JsBackend.AstToJsRegex
+func (jb *JsBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	body, flags, diags, err := jb.astToJsRegex(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "/" + body + "/" + flags, diags, nil
+}
+
+// AstToJsRegex produces the body and flags of a JavaScript regex from the AST, which can be used as 'new RegExp(body, flags)'
+func (jb *JsBackend) AstToJsRegex(ast *Ast) (body, flags string, err error) {
+	body, flags, _, err = jb.astToJsRegex(ast)
+	return body, flags, err
+}
+
+func (jb *JsBackend) astToJsRegex(ast *Ast) (body, flags string, diags []Diagnostic, err error) {
+
+	gb := &GoBackend{syntax: jsSyntax{}}
+	body, diags, err = gb.astToRegexBody(ast)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Make sure the query is also valid Go regex, so that JavaScript and Go agree on which queries are valid.
+	// Both walks reject the same functions, so this is only reached when the JavaScript walk found no errors, and each problem is reported once
+	_, _, err = (&GoBackend{}).AstToGoRegex(ast)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// An empty body would turn the regex literal into a comment, so we use an empty group like RegExp.prototype.source does
+	if body == "" {
+		body = "(?:)"
+	}
+
+	jb.Opts = gb.Opts
+	return body, jb.Flags(), diags, nil
+}
+
+// Flags returns the JavaScript regex flags equivalent to JsBackend.Opts, in the canonical order used by RegExp.prototype.flags
+func (jb *JsBackend) Flags() string {
+
+	flags := ""
+	if jb.Opts.FindAllMatches {
+		flags += "g"
+	}
+
+	if !jb.Opts.CaseSensitive {
+		flags += "i"
+	}
+
+	if jb.Opts.Multiline {
+		flags += "m"
+	}
+
+	if jb.Opts.DotMatchesNewline {
+		flags += "s"
+	}
+
+	return flags + "u"
+}
+
+// jsSyntax is the regexSyntax of JavaScript regex with the 'u' flag
+type jsSyntax struct{}
+
+var _ regexSyntax = jsSyntax{}
+
+func (jsSyntax) dialect() string {
+	return Dialect_JavaScript
+}
+
+// escapeString escapes the same characters as Go regex plus '/', which would otherwise end a regex literal.
+// All of them are syntax characters, so escaping them is valid with the 'u' flag
+func (jsSyntax) escapeString(original string) string {
+	return escapeChars(original, goSpecialChars+"/")
+}
+
+// anyChar doesn't use '.' because in JavaScript it doesn't match '\r', '\u2028' and '\u2029', while in Go it only doesn't match '\n'
+func (jsSyntax) anyChar(dotMatchesNewline bool) string {
+
+	if dotMatchesNewline {
+		return "."
+	}
+
+	return `[^\n]`
+}
+
+func (jsSyntax) endAnchor() string {
+	return "$"
+}
+
+// lineAnchor uses lookarounds when the 'm' flag isn't set, as JavaScript can't set flags for part of a regex.
+// For example '(?<![^\n])' matches where the character before is a new line or where there is no character before
+func (jsSyntax) lineAnchor(isStart, isMultiline bool) string {
+
+	switch {
+	case isMultiline && isStart:
+		return "^"
+	case isMultiline:
+		return "$"
+	case isStart:
+		return `(?<![^\n])`
+	default:
+		return `(?![^\n])`
+	}
+}
+
+// textAnchor uses lookarounds, as JavaScript has no '\A' and '\z' and its '^' and '$' match at lines when the 'm' flag is set
+func (jsSyntax) textAnchor(isStart bool) string {
+
+	if isStart {
+		return `(?<![\s\S])`
+	}
+
+	return `(?![\s\S])`
+}
+
+func (jsSyntax) hasLineFlags() bool {
+	return true
+}
+
+// namedCapture uses '(?<name>...)' because JavaScript doesn't support the '(?P<name>...)' form used by Go
+func (jsSyntax) namedCapture(name, regexString string) (string, bool) {
+	return "(?<" + name + ">" + regexString + ")", true
+}
+
+func (jsSyntax) nonCapturingGroup(regexString string) (string, bool) {
+	return "(?:" + regexString + ")", true
+}
+
+// charSet escapes '/' in addition to what Go escapes inside a character set. With the 'u' flag only syntax characters and '-'
+// can be escaped inside a character set, so other characters are written as is
+func (jsSyntax) charSet(cs *charSet) (string, bool) {
+	return cs.write(goCharSetSpecialChars + "/"), true
+}
+
+// charClass uses an explicit set for whitespace, because in JavaScript '\s' also matches unicode spaces, and spells out the ASCII
+// letters and word characters to match Go's '[[:alpha:]]' and '\w'. With the 'i' and 'u' flags case folding makes these sets also
+// match 'ſ' and the Kelvin sign, which is accepted as Go's '(?i)' does the same
+func (jsSyntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`\d`, `\D`},
+		charClass_Letter:     {`[A-Za-z]`, `[^A-Za-z]`},
+		charClass_Whitespace: {`[\t\n\f\r ]`, `[^\t\n\f\r ]`},
+		charClass_WordChar:   {`[0-9A-Za-z_]`, `[^0-9A-Za-z_]`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+// unicodeClass writes scripts as '\p{Script=name}', because with the 'u' flag JavaScript only accepts general categories without the property name
+func (jsSyntax) unicodeClass(name string, isScript, isNegated bool) (string, bool) {
+
+	if isScript {
+		name = "Script=" + name
+	}
+
+	return writeUnicodeClass(name, isNegated), true
+}
+
+// JavaScript supports lookarounds and backreferences, but they are rejected so that JsBackend accepts exactly the queries GoBackend accepts.
+// Atomic groups and possessive quantifiers aren't supported by JavaScript at all.
+
+func (jsSyntax) lookahead(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (jsSyntax) lookbehind(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (jsSyntax) lookbehindLimits() (isFixedWidth bool, maxWidth int) {
+	return false, 0
+}
+
+func (jsSyntax) backreference(name string) (string, bool) {
+	return "", false
+}
+
+func (jsSyntax) atomicGroup(regexString string) (string, bool) {
+	return "", false
+}
+
+func (jsSyntax) possessive(quantifiedRegexString string) (string, bool) {
+	return "", false
+}
+
+func (jsSyntax) lazy(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "?", true
+}
+
+func (jsSyntax) hasUngreedyFlag() bool {
+	return false
+}
+
+// scopedFlags uses the regex modifiers added in ES2025 (e.g. '(?i:...)'), which only allow the 'i', 'm' and 's' flags
+func (jsSyntax) scopedFlags(outer, inner RegexOptions) (string, bool) {
+	return writeScopedFlags(outer, inner, false), true
+}
+
+func (js jsSyntax) withOptions(opts RegexOptions) regexSyntax {
+	return js
+}
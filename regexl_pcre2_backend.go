@@ -0,0 +1,239 @@
+package regexl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pcre2Backend produces PCRE2 regex strings (as used by nginx, PHP etc), based on the rules here: https://www.pcre.org/current/doc/html/pcre2pattern.html
+//
+// Unlike GoBackend, Pcre2Backend supports lookarounds, backreferences, atomic groups and possessive quantifiers.
+// Options are applied as inline flags (e.g. '(?i)friend'), so the regex doesn't depend on how the host application passes options to PCRE2.
+type Pcre2Backend struct {
+	Opts RegexOptions
+}
+
+const Dialect_PCRE2 = "pcre2"
+
+func init() {
+	RegisterBackend(Dialect_PCRE2, func() Backend { return &Pcre2Backend{} })
+}
+
+var _ Backend = &Pcre2Backend{}
+
+func (pb *Pcre2Backend) Dialect() string {
+	return Dialect_PCRE2
+}
+
+func (pb *Pcre2Backend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	gb := &GoBackend{syntax: pcre2Syntax{}}
+	regexString, diags, err := gb.astToRegexBody(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Lookbehinds whose alternatives match text of different lengths are only errors when compiled by older PCRE2 versions, so they are noted instead
+	for _, n := range ast.Nodes {
+
+		Inspect(n, func(n Node) bool {
+
+			fExpr, ok := n.(*FuncExpr)
+			if !ok || !strings.HasSuffix(fExpr.Ident.Name, "preceded_by") || len(fExpr.Args) != 1 {
+				return true
+			}
+
+			for _, alt := range alternatives(fExpr.Args[0]) {
+
+				if minLen, maxLen := patternWidth(alt); minLen != maxLen {
+					diags = append(diags, Diagnostic{
+						Pos: alt.StartPos(),
+						Msg: fmt.Sprintf("pattern passed to function '%s' can match %s characters, which needs PCRE2 10.43 or later", fExpr.Ident.Name, describeWidth(minLen, maxLen)),
+					})
+				}
+			}
+
+			return true
+		})
+	}
+
+	pb.Opts = gb.Opts
+	return pb.ApplyOptionsToRegexString(regexString), diags, nil
+}
+
+func (pb *Pcre2Backend) ApplyOptionsToRegexString(regexString string) string {
+
+	flags := ""
+	if !pb.Opts.CaseSensitive {
+		flags += "i"
+	}
+
+	if pb.Opts.Multiline {
+		flags += "m"
+	}
+
+	if pb.Opts.DotMatchesNewline {
+		flags += "s"
+	}
+
+	if pb.Opts.Ungreedy {
+		flags += "U"
+	}
+
+	// Like Go, finding one or many matches is controlled by the host application (e.g. preg_match vs preg_match_all in PHP)
+	if flags == "" {
+		return regexString
+	}
+
+	return "(?" + flags + ")" + regexString
+}
+
+// alternatives returns the top level alternatives of the expression (e.g. 'a' and 'bc' of "'a' or 'bc'"), or the expression itself if it isn't an alternation
+func alternatives(e Expr) []Expr {
+
+	switch typedNode := e.(type) {
+
+	case *BinaryExpr:
+
+		if typedNode.Type == TokenType_Or {
+			return append(alternatives(typedNode.Lhs), alternatives(typedNode.Rhs)...)
+		}
+
+	case *FuncExpr:
+
+		if typedNode.Ident.Name == "any_strings_of" && len(typedNode.Args) > 0 {
+
+			alts := make([]Expr, 0, len(typedNode.Args))
+			for _, arg := range typedNode.Args {
+				alts = append(alts, alternatives(arg)...)
+			}
+
+			return alts
+		}
+	}
+
+	return []Expr{e}
+}
+
+// pcre2Syntax is the regexSyntax of PCRE2
+type pcre2Syntax struct{}
+
+var _ regexSyntax = pcre2Syntax{}
+
+func (pcre2Syntax) dialect() string {
+	return Dialect_PCRE2
+}
+
+// escapeString escapes the characters that are special either in a pattern or inside a character class, plus '/' which is commonly used as a delimiter (e.g. in PHP).
+// In PCRE2 a backslash followed by any non-alphanumeric character always matches that character
+func (pcre2Syntax) escapeString(original string) string {
+	return escapeChars(original, `\^$.|?*+()[]{}-/`)
+}
+
+func (pcre2Syntax) anyChar(dotMatchesNewline bool) string {
+	return "."
+}
+
+// endAnchor uses '\z' because in PCRE2 '$' also matches before a new line at the end of the text, while in Go it doesn't
+func (pcre2Syntax) endAnchor() string {
+	return `\z`
+}
+
+func (pcre2Syntax) lineAnchor(isStart, isMultiline bool) string {
+	return multilineAnchor(isStart, isMultiline)
+}
+
+func (pcre2Syntax) textAnchor(isStart bool) string {
+
+	if isStart {
+		return `\A`
+	}
+
+	return `\z`
+}
+
+func (pcre2Syntax) hasLineFlags() bool {
+	return true
+}
+
+func (pcre2Syntax) namedCapture(name, regexString string) (string, bool) {
+	return "(?<" + name + ">" + regexString + ")", true
+}
+
+func (pcre2Syntax) nonCapturingGroup(regexString string) (string, bool) {
+	return "(?:" + regexString + ")", true
+}
+
+// charSet escapes '/' in addition to what Go escapes inside a character set, so the set can be used with a '/' delimiter
+func (pcre2Syntax) charSet(cs *charSet) (string, bool) {
+	return cs.write(goCharSetSpecialChars + "/"), true
+}
+
+// charClass uses an explicit set for whitespace, because in PCRE2 '\s' also matches the vertical tab
+func (pcre2Syntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`\d`, `\D`},
+		charClass_Letter:     {`[[:alpha:]]`, `[[:^alpha:]]`},
+		charClass_Whitespace: {`[\t\n\f\r ]`, `[^\t\n\f\r ]`},
+		charClass_WordChar:   {`\w`, `\W`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+func (pcre2Syntax) unicodeClass(name string, isScript, isNegated bool) (string, bool) {
+	return writeUnicodeClass(name, isNegated), true
+}
+
+func (pcre2Syntax) lookahead(regexString string, isNegated bool) (string, bool) {
+
+	if isNegated {
+		return "(?!" + regexString + ")", true
+	}
+
+	return "(?=" + regexString + ")", true
+}
+
+func (pcre2Syntax) lookbehind(regexString string, isNegated bool) (string, bool) {
+
+	if isNegated {
+		return "(?<!" + regexString + ")", true
+	}
+
+	return "(?<=" + regexString + ")", true
+}
+
+// lookbehindLimits allows lookbehinds matching text of different lengths to match at most 255 characters, which is the default limit of PCRE2.
+// Before PCRE2 10.43 each alternative of a lookbehind had to match a fixed number of characters, which Pcre2Backend notes with a Diagnostic
+func (pcre2Syntax) lookbehindLimits() (isFixedWidth bool, maxWidth int) {
+	return false, 255
+}
+
+func (pcre2Syntax) backreference(name string) (string, bool) {
+	return `\k<` + name + ">", true
+}
+
+func (pcre2Syntax) atomicGroup(regexString string) (string, bool) {
+	return "(?>" + regexString + ")", true
+}
+
+func (pcre2Syntax) possessive(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "+", true
+}
+
+func (pcre2Syntax) lazy(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "?", true
+}
+
+func (pcre2Syntax) hasUngreedyFlag() bool {
+	return true
+}
+
+func (pcre2Syntax) scopedFlags(outer, inner RegexOptions) (string, bool) {
+	return writeScopedFlags(outer, inner, true), true
+}
+
+func (ps pcre2Syntax) withOptions(opts RegexOptions) regexSyntax {
+	return ps
+}
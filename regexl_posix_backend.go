@@ -0,0 +1,318 @@
+package regexl
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PosixBackend produces POSIX Extended Regular Expressions (ERE) as used by 'grep -E', 'awk' and 'sed -E', based on the rules here:
+// https://pubs.opengroup.org/onlinepubs/9699919799/basedefs/V1_chap09.html#tag_09_04
+//
+// ERE has no flags, so case insensitive queries add the other cases of each letter instead (e.g. 'a' becomes '[aA]').
+// ERE also has no non-capturing or named groups, so capturing groups are used instead and a Diagnostic is produced, as capture group numbers change.
+// Character ranges are only well defined in the C locale, so the range '0-9' is written as '[:digit:]'.
+type PosixBackend struct {
+	Opts RegexOptions
+}
+
+const Dialect_POSIX_ERE = "posix-ere"
+
+func init() {
+	RegisterBackend(Dialect_POSIX_ERE, func() Backend { return &PosixBackend{} })
+}
+
+var _ Backend = &PosixBackend{}
+
+func (pb *PosixBackend) Dialect() string {
+	return Dialect_POSIX_ERE
+}
+
+func (pb *PosixBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	// Case insensitivity is part of how literals are written, so options must be known before the regex is produced
+	gb := &GoBackend{syntax: posixSyntax{}}
+	_, _, err := gb.astToRegexBody(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	gb = &GoBackend{syntax: posixSyntax{isCaseInsensitive: !gb.Opts.CaseSensitive}}
+	regexString, diags, err := gb.astToRegexBody(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Like Go, finding one or many matches is controlled by the tool (e.g. 'grep -o' or the 'g' flag of the sed 's' command)
+	pb.Opts = gb.Opts
+	return regexString, diags, nil
+}
+
+// posixSyntax is the regexSyntax of POSIX ERE
+type posixSyntax struct {
+	isCaseInsensitive bool
+}
+
+var _ regexSyntax = posixSyntax{}
+
+func (posixSyntax) dialect() string {
+	return Dialect_POSIX_ERE
+}
+
+func (ps posixSyntax) escapeString(original string) string {
+
+	sb := strings.Builder{}
+
+	for _, r := range original {
+
+		if ps.isCaseInsensitive {
+
+			if variants := caseVariants(r); len(variants) > 1 {
+				sb.WriteString("[" + string(variants) + "]")
+				continue
+			}
+		}
+
+		if strings.ContainsRune(`.[\()*+?{|^$`, r) {
+			sb.WriteRune('\\')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// anyChar uses '.', which unlike Go also matches a new line. This doesn't matter for line based tools like grep, and ERE has no way to exclude a new line
+func (posixSyntax) anyChar(dotMatchesNewline bool) string {
+	return "."
+}
+
+func (posixSyntax) endAnchor() string {
+	return "$"
+}
+
+// lineAnchor is the same as textAnchor, as tools using ERE (e.g. grep) match each line on its own
+func (posixSyntax) lineAnchor(isStart, isMultiline bool) string {
+	return posixSyntax{}.textAnchor(isStart)
+}
+
+func (posixSyntax) textAnchor(isStart bool) string {
+
+	if isStart {
+		return "^"
+	}
+
+	return "$"
+}
+
+func (posixSyntax) hasLineFlags() bool {
+	return false
+}
+
+// charClass uses POSIX bracket expressions, as ERE has no '\d', '\s' or '\w'. Backslashes have no special meaning inside brackets,
+// so whitespace is '[[:space:]]', which unlike the other dialects also matches the vertical tab
+func (posixSyntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`[[:digit:]]`, `[^[:digit:]]`},
+		charClass_Letter:     {`[[:alpha:]]`, `[^[:alpha:]]`},
+		charClass_Whitespace: {`[[:space:]]`, `[^[:space:]]`},
+		charClass_WordChar:   {`[[:alnum:]_]`, `[^[:alnum:]_]`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+// unicodeClass isn't supported because ERE has no way to match unicode categories or scripts
+func (posixSyntax) unicodeClass(name string, isScript, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) namedCapture(name, regexString string) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) nonCapturingGroup(regexString string) (string, bool) {
+	return "", false
+}
+
+// charSet writes the set without escapes, as backslashes have no special meaning inside brackets in ERE.
+// Instead, special characters are moved to where they are literal: ']' first, '-' last, '^' anywhere but first and '[' near the end so it isn't followed by '.', ':' or '='
+func (ps posixSyntax) charSet(cs *charSet) (string, bool) {
+
+	items := cs.Items
+	if ps.isCaseInsensitive {
+		items = caseFoldCharSetItems(items)
+	}
+
+	hasCloseBracket, hasOpenBracket, hasCaret, hasDash := false, false, false, false
+	sb := strings.Builder{}
+
+	for _, item := range items {
+
+		if item.Class != "" {
+			sb.WriteString(item.Class)
+			continue
+		}
+
+		if item.IsRange() {
+
+			if strings.ContainsAny(string([]rune{item.From, item.To}), "[]^-") {
+				return "", false
+			}
+
+			if item.From == '0' && item.To == '9' {
+				sb.WriteString("[:digit:]")
+				continue
+			}
+
+			sb.WriteString(string(item.From) + "-" + string(item.To))
+			continue
+		}
+
+		switch item.From {
+		case ']':
+			hasCloseBracket = true
+		case '[':
+			hasOpenBracket = true
+		case '^':
+			hasCaret = true
+		case '-':
+			hasDash = true
+		default:
+			sb.WriteRune(item.From)
+		}
+	}
+
+	content := sb.String()
+	if hasCloseBracket {
+		content = "]" + content
+	}
+
+	if hasOpenBracket {
+		content += "["
+	}
+
+	setStart := "["
+	if cs.IsNegated {
+		setStart = "[^"
+	}
+
+	if hasCaret {
+
+		// Only '^' and maybe '-' are in the set, so '^' can't be last. A negated set can start with a literal '^' as it comes after the negating one
+		switch {
+		case content == "" && hasDash:
+			return setStart + "-^]", true
+		case content == "" && cs.IsNegated:
+			return "[^^]", true
+		case content == "":
+			return `\^`, true
+		}
+
+		content += "^"
+	}
+
+	if hasDash {
+		content += "-"
+	}
+
+	return setStart + content + "]", true
+}
+
+// ERE has no lookarounds, backreferences (they are only in basic regular expressions), atomic groups or possessive quantifiers
+
+func (posixSyntax) lookahead(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) lookbehind(regexString string, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) backreference(name string) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) lookbehindLimits() (isFixedWidth bool, maxWidth int) {
+	return false, 0
+}
+
+func (posixSyntax) atomicGroup(regexString string) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) possessive(quantifiedRegexString string) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) lazy(quantifiedRegexString string) (string, bool) {
+	return "", false
+}
+
+func (posixSyntax) hasUngreedyFlag() bool {
+	return false
+}
+
+func (posixSyntax) scopedFlags(outer, inner RegexOptions) (string, bool) {
+	return "", false
+}
+
+// withOptions writes literals with the case sensitivity of the options, which is the only option a scope can change in ERE
+func (posixSyntax) withOptions(opts RegexOptions) regexSyntax {
+	return posixSyntax{isCaseInsensitive: !opts.CaseSensitive}
+}
+
+// caseVariants returns r and the other cases of r (e.g. 'a' and 'A'), with r first.
+// ASCII characters only get ASCII variants, so that 'k' doesn't also match the Kelvin sign
+func caseVariants(r rune) []rune {
+
+	variants := []rune{r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+
+		if r < utf8.RuneSelf && f >= utf8.RuneSelf {
+			continue
+		}
+
+		variants = append(variants, f)
+	}
+
+	return variants
+}
+
+// caseFoldCharSetItems returns the items plus the other cases of all characters in them, where the added characters are merged into ranges (e.g. 'A-Z' adds 'a-z')
+func caseFoldCharSetItems(items []charSetItem) []charSetItem {
+
+	addedRunes := make([]rune, 0)
+	for _, item := range items {
+
+		for r := item.From; r <= item.To; r++ {
+
+			for _, v := range caseVariants(r)[1:] {
+
+				if v < item.From || v > item.To {
+					addedRunes = append(addedRunes, v)
+				}
+			}
+		}
+	}
+
+	slices.Sort(addedRunes)
+	addedRunes = slices.Compact(addedRunes)
+
+	foldedItems := slices.Clone(items)
+	for i := 0; i < len(addedRunes); i++ {
+
+		item := charSetItem{From: addedRunes[i], To: addedRunes[i]}
+		for i+1 < len(addedRunes) && addedRunes[i+1] == item.To+1 {
+			item.To = addedRunes[i+1]
+			i++
+		}
+
+		foldedItems = append(foldedItems, item)
+	}
+
+	return foldedItems
+}
@@ -0,0 +1,241 @@
+package regexl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PythonBackend produces regex strings for the Python 're' module, based on the rules here: https://docs.python.org/3/library/re.html
+//
+// The produced regex starts with inline flags (e.g. '(?i)friend'), so it can be used as-is with 're.compile(pattern)'.
+// PythonBackend.AstToPythonRegex returns the pattern and the equivalent flags (e.g. 're.IGNORECASE') separately.
+type PythonBackend struct {
+	Opts RegexOptions
+}
+
+const Dialect_Python = "python"
+
+func init() {
+	RegisterBackend(Dialect_Python, func() Backend { return &PythonBackend{} })
+}
+
+var _ Backend = &PythonBackend{}
+
+func (pb *PythonBackend) Dialect() string {
+	return Dialect_Python
+}
+
+// AstToRegexString produces a Python regex string with inline flags (e.g. '(?i)friend') from the AST by calling PythonBackend.AstToPythonRegex
+func (pb *PythonBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	pattern, _, diags, err := pb.AstToPythonRegex(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return pb.InlineFlags() + pattern, diags, nil
+}
+
+// AstToPythonRegex produces a Python regex pattern without inline flags from the AST, and the Python flags that should be used with it,
+// which can be used as 're.compile(pattern, flags)'. The diagnostics are the same as the ones of PythonBackend.AstToRegexString
+func (pb *PythonBackend) AstToPythonRegex(ast *Ast) (pattern, flags string, diags []Diagnostic, err error) {
+
+	gb := &GoBackend{syntax: pythonSyntax{}}
+	pattern, diags, err = gb.astToRegexBody(ast)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Atomic groups and possessive quantifiers are only errors when compiled by older Python versions, so they are noted instead
+	for _, n := range ast.Nodes {
+
+		Inspect(n, func(n Node) bool {
+
+			if fExpr, ok := n.(*FuncExpr); ok && (fExpr.Ident.Name == "atomic" || strings.HasPrefix(fExpr.Ident.Name, "possessive_")) {
+				diags = append(diags, Diagnostic{
+					Pos: fExpr.StartPos(),
+					Msg: fmt.Sprintf("function '%s' needs Python 3.11 or later", fExpr.Ident.Name),
+				})
+			}
+
+			return true
+		})
+	}
+
+	pb.Opts = gb.Opts
+	return pattern, pb.Flags(), diags, nil
+}
+
+// Flags returns the Python flags equivalent to PythonBackend.Opts as a Python expression (e.g. 're.IGNORECASE'), or '0' if there are no flags
+func (pb *PythonBackend) Flags() string {
+
+	flags := make([]string, 0, 3)
+	if !pb.Opts.CaseSensitive {
+		flags = append(flags, "re.IGNORECASE")
+	}
+
+	if pb.Opts.Multiline {
+		flags = append(flags, "re.MULTILINE")
+	}
+
+	if pb.Opts.DotMatchesNewline {
+		flags = append(flags, "re.DOTALL")
+	}
+
+	if len(flags) == 0 {
+		return "0"
+	}
+
+	return strings.Join(flags, "|")
+}
+
+// InlineFlags returns the Python inline flags equivalent to PythonBackend.Opts (e.g. '(?i)'), or an empty string if there are no flags.
+// Unlike Go, Python doesn't allow an empty '(?)' group
+func (pb *PythonBackend) InlineFlags() string {
+
+	flags := ""
+	if !pb.Opts.CaseSensitive {
+		flags += "i"
+	}
+
+	if pb.Opts.Multiline {
+		flags += "m"
+	}
+
+	if pb.Opts.DotMatchesNewline {
+		flags += "s"
+	}
+
+	if flags == "" {
+		return ""
+	}
+
+	return "(?" + flags + ")"
+}
+
+// pythonSyntax is the regexSyntax of the Python 're' module
+type pythonSyntax struct{}
+
+var _ regexSyntax = pythonSyntax{}
+
+func (pythonSyntax) dialect() string {
+	return Dialect_Python
+}
+
+// escapeString escapes the same characters as 're.escape' does since Python 3.7, which are the characters
+// that are special either in a pattern or inside a set (e.g. '-' and '&'), plus whitespace and '#' which are special in verbose patterns
+func (pythonSyntax) escapeString(original string) string {
+	return escapeChars(original, "()[]{}?*+-|^$\\.&~# \t\n\r\v\f")
+}
+
+func (pythonSyntax) anyChar(dotMatchesNewline bool) string {
+	return "."
+}
+
+// endAnchor uses '\Z' because in Python '$' also matches before a new line at the end of the text, while in Go it doesn't
+func (pythonSyntax) endAnchor() string {
+	return `\Z`
+}
+
+func (pythonSyntax) lineAnchor(isStart, isMultiline bool) string {
+	return multilineAnchor(isStart, isMultiline)
+}
+
+// textAnchor uses '\Z' for the end, which in Python only matches at the very end of the text like '\z' in Go
+func (pythonSyntax) textAnchor(isStart bool) string {
+
+	if isStart {
+		return `\A`
+	}
+
+	return `\Z`
+}
+
+func (pythonSyntax) hasLineFlags() bool {
+	return true
+}
+
+func (pythonSyntax) namedCapture(name, regexString string) (string, bool) {
+	return "(?P<" + name + ">" + regexString + ")", true
+}
+
+func (pythonSyntax) nonCapturingGroup(regexString string) (string, bool) {
+	return "(?:" + regexString + ")", true
+}
+
+// charSet escapes '&', '~' and '|' in addition to what Go escapes inside a character set, because Python warns that
+// '&&', '~~' and '||' inside a set might become set operations in the future
+func (pythonSyntax) charSet(cs *charSet) (string, bool) {
+	return cs.write(goCharSetSpecialChars + "&~|"), true
+}
+
+// charClass uses explicit sets, because in Python '\d', '\s' and '\w' match unicode characters and POSIX classes like '[[:alpha:]]' aren't supported
+func (pythonSyntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`[0-9]`, `[^0-9]`},
+		charClass_Letter:     {`[A-Za-z]`, `[^A-Za-z]`},
+		charClass_Whitespace: {`[\t\n\f\r ]`, `[^\t\n\f\r ]`},
+		charClass_WordChar:   {`[0-9A-Za-z_]`, `[^0-9A-Za-z_]`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+// unicodeClass isn't supported because the 're' module has no '\p{...}' classes, which are only in the third party 'regex' module
+func (pythonSyntax) unicodeClass(name string, isScript, isNegated bool) (string, bool) {
+	return "", false
+}
+
+func (pythonSyntax) lookahead(regexString string, isNegated bool) (string, bool) {
+
+	if isNegated {
+		return "(?!" + regexString + ")", true
+	}
+
+	return "(?=" + regexString + ")", true
+}
+
+func (pythonSyntax) lookbehind(regexString string, isNegated bool) (string, bool) {
+
+	if isNegated {
+		return "(?<!" + regexString + ")", true
+	}
+
+	return "(?<=" + regexString + ")", true
+}
+
+// lookbehindLimits requires a fixed width, as Python rejects lookbehinds that can match text of different lengths (e.g. '(?<=a+)' or '(?<=a|bc)')
+func (pythonSyntax) lookbehindLimits() (isFixedWidth bool, maxWidth int) {
+	return true, 0
+}
+
+func (pythonSyntax) backreference(name string) (string, bool) {
+	return "(?P=" + name + ")", true
+}
+
+// atomicGroup requires Python 3.11 or later, which PythonBackend notes with a Diagnostic
+func (pythonSyntax) atomicGroup(regexString string) (string, bool) {
+	return "(?>" + regexString + ")", true
+}
+
+// possessive requires Python 3.11 or later, which PythonBackend notes with a Diagnostic
+func (pythonSyntax) possessive(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "+", true
+}
+
+func (pythonSyntax) lazy(quantifiedRegexString string) (string, bool) {
+	return quantifiedRegexString + "?", true
+}
+
+func (pythonSyntax) hasUngreedyFlag() bool {
+	return false
+}
+
+func (pythonSyntax) scopedFlags(outer, inner RegexOptions) (string, bool) {
+	return writeScopedFlags(outer, inner, false), true
+}
+
+func (ps pythonSyntax) withOptions(opts RegexOptions) regexSyntax {
+	return ps
+}
@@ -0,0 +1,98 @@
+package regexl
+
+// RustBackend produces regex strings for the Rust 'regex' crate, based on the rules here: https://docs.rs/regex/latest/regex/#syntax
+//
+// The syntax of the Rust 'regex' crate is very close to Go regex as both are based on RE2, so RustBackend uses the Go regex syntax except where the two differ.
+type RustBackend struct {
+	Opts RegexOptions
+}
+
+const Dialect_Rust = "rust"
+
+func init() {
+	RegisterBackend(Dialect_Rust, func() Backend { return &RustBackend{} })
+}
+
+var _ Backend = &RustBackend{}
+
+func (rb *RustBackend) Dialect() string {
+	return Dialect_Rust
+}
+
+func (rb *RustBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	gb := &GoBackend{syntax: rustSyntax{}}
+	regexString, diags, err := gb.astToRegexBody(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rb.Opts = gb.Opts
+	return rb.ApplyOptionsToRegexString(regexString), diags, nil
+}
+
+// ApplyOptionsToRegexString adds the options as inline flags. Unlike Go, Rust doesn't allow an empty '(?)' group, so nothing is added if there are no flags
+func (rb *RustBackend) ApplyOptionsToRegexString(regexString string) string {
+
+	flags := ""
+	if !rb.Opts.CaseSensitive {
+		flags += "i"
+	}
+
+	if rb.Opts.Multiline {
+		flags += "m"
+	}
+
+	if rb.Opts.DotMatchesNewline {
+		flags += "s"
+	}
+
+	if rb.Opts.Ungreedy {
+		flags += "U"
+	}
+
+	// Like Go, finding one or many matches is controlled by the function used (e.g. Regex::find vs Regex::find_iter)
+	if flags == "" {
+		return regexString
+	}
+
+	return "(?" + flags + ")" + regexString
+}
+
+// rustSyntax is the regexSyntax of the Rust 'regex' crate, which is the Go regex syntax except where overridden.
+//
+// Named captures keep the '(?P<name>...)' form, as it is supported by all versions of the crate while '(?<name>...)' needs version 1.9 or later
+type rustSyntax struct {
+	goSyntax
+}
+
+var _ regexSyntax = rustSyntax{}
+
+func (rustSyntax) dialect() string {
+	return Dialect_Rust
+}
+
+// charClass uses ASCII classes, because in Rust '\d', '\s' and '\w' match unicode characters.
+// Whitespace uses an explicit set as '[[:space:]]' also matches the vertical tab
+func (rustSyntax) charClass(class charClass, isNegated bool) string {
+
+	classes := map[charClass][2]string{
+		charClass_Digit:      {`[[:digit:]]`, `[[:^digit:]]`},
+		charClass_Letter:     {`[[:alpha:]]`, `[[:^alpha:]]`},
+		charClass_Whitespace: {`[\t\n\f\r ]`, `[^\t\n\f\r ]`},
+		charClass_WordChar:   {`[[:word:]]`, `[[:^word:]]`},
+	}
+
+	return pickCharClass(classes[class], isNegated)
+}
+
+// charSet escapes '&' and '~' in addition to what Go escapes (which includes '-'), because in Rust '&&', '~~' and '--' inside a character set
+// are the intersection, symmetric difference and difference operators
+func (rustSyntax) charSet(cs *charSet) (string, bool) {
+	return cs.write(goCharSetSpecialChars + "&~"), true
+}
+
+// withOptions is overridden so that the embedded goSyntax doesn't replace the Rust syntax
+func (rs rustSyntax) withOptions(opts RegexOptions) regexSyntax {
+	return rs
+}
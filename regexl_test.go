@@ -1,6 +1,14 @@
 package regexl
 
 import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -153,7 +161,16 @@ func TestMain(t *testing.T) {
 				select starts_with('Hello there, ') + one_plus_of(any_chars_of(from_to('A', 'Z'), '.!-'))
 				`,
 			},
-			expectedRegex: "(?i)^Hello there, (?:[A-Z\\.!-])+",
+			expectedRegex: "(?i)^Hello there, (?:[A-Z.!\\-])+",
+		},
+		{
+			desc: "Escaping metacharacters",
+			rl: Regexl{
+				Query: `
+				select 'a+b*c?' + 'x|y^$' + '(1.5)[2]{3}' + any_chars_of(']^-[')
+				`,
+			},
+			expectedRegex: `(?i)a\+b\*c\?x\|y\^\$\(1\.5\)\[2\]\{3\}[\]\^\-\[]`,
 		},
 		{
 			desc: "Email query",
@@ -183,7 +200,31 @@ func TestMain(t *testing.T) {
 					)
 				`,
 			},
-			expectedRegex: "(?i)(?:[A-Z0-9\\._%+-])+@(?:[A-Z0-9\\.-])+\\.[A-Z]{2,10}",
+			expectedRegex: "(?i)(?:[A-Z0-9._%+\\-])+@(?:[A-Z0-9.\\-])+\\.[A-Z]{2,10}",
+		},
+		{
+			desc: "Func: capture",
+			rl: Regexl{
+				Query: `
+				select 'Hello ' + capture(one_plus_of(any_chars_of(from_to('a', 'z'))))
+				`,
+			},
+			expectedRegex: "(?i)Hello ((?:[a-z])+)",
+		},
+		{
+			desc: "Func: capture_as",
+			rl: Regexl{
+				Query: `
+				set_options({
+					case_sensitive: true,
+				})
+				select
+					capture_as('year', count_between(any_chars_of(from_to(0, 9)), 4, 4)) +
+					'-' +
+					capture_as('month', count_between(any_chars_of(from_to(0, 9)), 2, 2))
+				`,
+			},
+			expectedRegex: "(?)(?P<year>[0-9]{4,4})-(?P<month>[0-9]{2,2})",
 		},
 		{
 			desc: "Crazy formatting 1",
@@ -285,6 +326,33 @@ func TestMain(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			desc: "Invalid capture_as: duplicate name",
+			rl: Regexl{
+				Query: `
+				select capture_as('word', 'a') + capture_as('word', 'b')
+				`,
+			},
+			shouldError: true,
+		},
+		{
+			desc: "Invalid capture_as: bad name",
+			rl: Regexl{
+				Query: `
+				select capture_as('1st word', 'a')
+				`,
+			},
+			shouldError: true,
+		},
+		{
+			desc: "Invalid capture_as: non-string name",
+			rl: Regexl{
+				Query: `
+				select capture_as(one_plus_of('a'), 'a')
+				`,
+			},
+			shouldError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -317,3 +385,2344 @@ func TestMain(t *testing.T) {
 		}
 	}
 }
+
+type upperCaseTestBackend struct{}
+
+func (b *upperCaseTestBackend) Dialect() string { return "test-upper" }
+
+func (b *upperCaseTestBackend) AstToRegexString(ast *Ast) (string, []Diagnostic, error) {
+
+	regexString, _, err := (&GoBackend{}).AstToRegexString(ast)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return strings.ToUpper(regexString), []Diagnostic{{Pos: 0, Msg: "upper cased"}}, nil
+}
+
+// TestEscaping checks that literals with regex metacharacters match only themselves, both as strings and inside any_chars_of.
+// Go regex accepts the escapes of all the dialects checked here, so the produced regexes are run with the regexp package
+func TestEscaping(t *testing.T) {
+
+	literals := []string{"a+b", "1.5*2?", "x|y", "^$", "(a)[b]{c}", "100%", "a-b", "a/b", "&~#", "[:alpha:]"}
+	dialects := []string{Dialect_Go, Dialect_Rust, Dialect_PCRE2}
+
+	for _, dialect := range dialects {
+
+		for _, lit := range literals {
+
+			regexString, err := NewRegexl("set_options({case_sensitive: true}) select '" + lit + "'").CompileString(dialect)
+			if err != nil {
+				t.Fatalf("Compiling failed. Dialect=%s; Literal=%s; Err=%v\n", dialect, lit, err)
+			}
+
+			re, err := regexp.Compile("^(?:" + regexString + ")$")
+			if err != nil {
+				t.Fatalf("Produced regex is invalid. Dialect=%s; Regex=%s; Err=%v\n", dialect, regexString, err)
+			}
+
+			if !re.MatchString(lit) {
+				t.Errorf("Regex doesn't match its literal. Dialect=%s; Literal=%s; Regex=%s\n", dialect, lit, regexString)
+			}
+
+			regexString, err = NewRegexl("set_options({case_sensitive: true}) select any_chars_of('" + lit + "')").CompileString(dialect)
+			if err != nil {
+				t.Fatalf("Compiling failed. Dialect=%s; Literal=%s; Err=%v\n", dialect, lit, err)
+			}
+
+			re, err = regexp.Compile("^(?:" + regexString + ")$")
+			if err != nil {
+				t.Fatalf("Produced regex is invalid. Dialect=%s; Regex=%s; Err=%v\n", dialect, regexString, err)
+			}
+
+			for _, r := range lit {
+				if !re.MatchString(string(r)) {
+					t.Errorf("Character set doesn't match one of its characters. Dialect=%s; Char=%c; Regex=%s\n", dialect, r, regexString)
+				}
+			}
+		}
+	}
+
+	// A '-' between two characters must not make a range
+	re := NewRegexl("select one_plus_of(any_chars_of('a-c'))").MustCompile().CompiledRegexp
+	if re.MatchString("b") {
+		t.Errorf("Character set 'a-c' matched 'b'. Regex=%s\n", re.String())
+	}
+
+	// 'a+b' must not match 'aab'
+	re = NewRegexl("select 'a+b'").MustCompile().CompiledRegexp
+	if re.MatchString("aab") {
+		t.Errorf("Literal 'a+b' matched 'aab'. Regex=%s\n", re.String())
+	}
+}
+
+func TestCharClasses(t *testing.T) {
+
+	query := `
+	set_options({
+		case_sensitive: true,
+	})
+	select digit() + not_digit() + letter() + not_letter() + whitespace() + not_whitespace() + word_char() + not_word_char() + any_char()
+	`
+
+	testCases := []struct {
+		dialect       string
+		expectedRegex string
+	}{
+		{
+			dialect:       Dialect_Go,
+			expectedRegex: `(?)\d\D[[:alpha:]][[:^alpha:]]\s\S\w\W.`,
+		},
+		{
+			dialect:       Dialect_JavaScript,
+			expectedRegex: `/\d\D[A-Za-z][^A-Za-z][\t\n\f\r ][^\t\n\f\r ][0-9A-Za-z_][^0-9A-Za-z_][^\n]/u`,
+		},
+		{
+			dialect:       Dialect_Python,
+			expectedRegex: `[0-9][^0-9][A-Za-z][^A-Za-z][\t\n\f\r ][^\t\n\f\r ][0-9A-Za-z_][^0-9A-Za-z_].`,
+		},
+		{
+			dialect:       Dialect_PCRE2,
+			expectedRegex: `\d\D[[:alpha:]][[:^alpha:]][\t\n\f\r ][^\t\n\f\r ]\w\W.`,
+		},
+		{
+			dialect:       Dialect_Rust,
+			expectedRegex: `[[:digit:]][[:^digit:]][[:alpha:]][[:^alpha:]][\t\n\f\r ][^\t\n\f\r ][[:word:]][[:^word:]].`,
+		},
+		{
+			dialect:       Dialect_POSIX_ERE,
+			expectedRegex: `[[:digit:]][^[:digit:]][[:alpha:]][^[:alpha:]][[:space:]][^[:space:]][[:alnum:]_][^[:alnum:]_].`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+
+	re := NewRegexl(query).MustCompile().CompiledRegexp
+	if !re.MatchString("7xA5\tz_.?") {
+		t.Errorf("Character classes don't match. Regex=%s\n", re.String())
+	}
+
+	if re.MatchString("7xA5\tz_a?") {
+		t.Errorf("not_word_char matched a letter. Regex=%s\n", re.String())
+	}
+
+	// Case folding makes the ASCII letters also match 'ſ' and the Kelvin sign, in Go with '(?i)' and in JavaScript with the 'i' and 'u' flags
+	re = NewRegexl("select starts_with(ends_with(one_plus_of(letter())))").MustCompile().CompiledRegexp
+	if !re.MatchString("sſk\u212a") {
+		t.Errorf("Case insensitive letter doesn't match 'ſ' and the Kelvin sign. Regex=%s\n", re.String())
+	}
+
+	if regexString, _ := NewRegexl("select letter() + word_char()").CompileString(Dialect_JavaScript); regexString != `/[A-Za-z][0-9A-Za-z_]/iu` {
+		t.Errorf("Case insensitive letter and word_char aren't ASCII sets with the 'i' and 'u' flags. Compiled=%s\n", regexString)
+	}
+
+	if _, err := NewRegexl("select digit('a')").CompileString(Dialect_Go); err == nil {
+		t.Errorf("Passing an argument to digit should fail\n")
+	}
+}
+
+func TestUnicodeClasses(t *testing.T) {
+
+	query := `
+	set_options({
+		case_sensitive: true,
+	})
+	select one_plus_of(any_chars_of(unicode_category('L'), not_unicode(unicode_script('Greek')), '_')) + unicode_script('Han')
+	`
+
+	testCases := []struct {
+		dialect       string
+		expectedRegex string
+		shouldError   bool
+	}{
+		{
+			dialect:       Dialect_Go,
+			expectedRegex: `(?)(?:[\p{L}\P{Greek}_])+\p{Han}`,
+		},
+		{
+			dialect:       Dialect_JavaScript,
+			expectedRegex: `/(?:[\p{L}\P{Script=Greek}_])+\p{Script=Han}/u`,
+		},
+		{
+			dialect:       Dialect_PCRE2,
+			expectedRegex: `(?:[\p{L}\P{Greek}_])+\p{Han}`,
+		},
+		{
+			dialect:       Dialect_Rust,
+			expectedRegex: `(?:[\p{L}\P{Greek}_])+\p{Han}`,
+		},
+		{
+			dialect:     Dialect_Python,
+			shouldError: true,
+		},
+		{
+			dialect:     Dialect_POSIX_ERE,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+
+				if tc.shouldError {
+
+					var backendErr *BackendError
+					if !errors.As(err, &backendErr) || !strings.Contains(err.Error(), "unicode categories and scripts") {
+						t.Errorf("Expected an unsupported error. Err=%v\n", err)
+					}
+
+					return
+				}
+
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if tc.shouldError {
+				t.Fatalf("Compilation should have thrown an error but didn't. Regex=%s\n", regexString)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+
+	re := NewRegexl("select starts_with(ends_with(one_plus_of(unicode_category('Lu')) + unicode_script('Greek')))").MustCompile().CompiledRegexp
+	if !re.MatchString("ÉÖλ") || re.MatchString("ÉÖL") {
+		t.Errorf("Unicode classes don't match the right characters. Regex=%s\n", re.String())
+	}
+
+	// Like ranges, case categories follow the case insensitivity of the query
+	caseCases := []struct {
+		query       string
+		matchesA    bool
+		matchesLowA bool
+	}{
+		{query: "select unicode_category('Lu')", matchesA: true, matchesLowA: true},
+		{query: "set_options({case_sensitive: true}) select unicode_category('Lu')", matchesA: true, matchesLowA: false},
+		{query: "select not_unicode(unicode_category('Lu'))", matchesA: false, matchesLowA: false},
+		{query: "set_options({case_sensitive: true}) select not_unicode(unicode_category('Lu'))", matchesA: false, matchesLowA: true},
+	}
+
+	for _, tc := range caseCases {
+
+		re := NewRegexl(tc.query).MustCompile().CompiledRegexp
+		if re.MatchString("A") != tc.matchesA || re.MatchString("a") != tc.matchesLowA {
+			t.Errorf("Unicode class doesn't follow the case sensitivity of the query. Regex=%s; Query=%s\n", re.String(), tc.query)
+		}
+	}
+
+	errorCases := []struct {
+		query string
+		// expectedSpan is the part of the query the error is about
+		expectedSpan string
+	}{
+		{query: "select unicode_category('lu')", expectedSpan: "'lu'"},
+		{query: "select unicode_script('Klingon')", expectedSpan: "'Klingon'"},
+		{query: "select not_unicode(unicode_category(5))", expectedSpan: "5"},
+		{query: "select not_unicode('a')", expectedSpan: "'a'"},
+		{query: "select any_chars_of('a', unicode_category())", expectedSpan: "unicode_category()"},
+	}
+
+	for _, tc := range errorCases {
+
+		err := NewRegexl(tc.query).Compile()
+
+		errs := Errors(err)
+		if len(errs) != 1 {
+			t.Errorf("Expected one error but got %d. Err=%v; Query=%s\n", len(errs), err, tc.query)
+			continue
+		}
+
+		span, _ := ErrorSpan(tc.query, errs[0])
+		if got := tc.query[span.Start.Pos:span.End.Pos]; got != tc.expectedSpan {
+			t.Errorf("Error has the wrong span. Expected=%s; Got=%s; Err=%v\n", tc.expectedSpan, got, err)
+		}
+	}
+}
+
+func TestNegatedCharSets(t *testing.T) {
+
+	query := `
+	set_options({
+		case_sensitive: true,
+	})
+	select '"' + zero_plus_of(none_of_chars('^"', from_to('a', 'f'), letter(), digit())) + '"'
+	`
+
+	testCases := []struct {
+		dialect       string
+		expectedRegex string
+	}{
+		{
+			dialect:       Dialect_Go,
+			expectedRegex: `(?)"(?:[^\^"a-f[:alpha:]\d])*"`,
+		},
+		{
+			dialect:       Dialect_JavaScript,
+			expectedRegex: `/"(?:[^\^"a-fA-Za-z\d])*"/u`,
+		},
+		{
+			dialect:       Dialect_Python,
+			expectedRegex: `"(?:[^\^"a-fA-Za-z0-9])*"`,
+		},
+		{
+			dialect:       Dialect_PCRE2,
+			expectedRegex: `"(?:[^\^"a-f[:alpha:]\d])*"`,
+		},
+		{
+			dialect:       Dialect_Rust,
+			expectedRegex: `"(?:[^\^"a-f[:alpha:][:digit:]])*"`,
+		},
+		{
+			dialect:       Dialect_POSIX_ERE,
+			expectedRegex: `"([^"a-f[:alpha:][:digit:]^])*"`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+
+	re := NewRegexl(query).MustCompile().CompiledRegexp
+	if !re.MatchString(`say "-+" now`) || re.MatchString(`"-^"`) || re.MatchString(`"-b"`) {
+		t.Errorf("Negated character set doesn't match the right characters. Regex=%s\n", re.String())
+	}
+
+	// Only a '^' in a negated POSIX set must not be taken as the negation
+	regexString, err := NewRegexl("select none_of_chars('^')").CompileString(Dialect_POSIX_ERE)
+	if err != nil || regexString != "[^^]" {
+		t.Errorf("Negated POSIX set of '^' is wrong. Expected=[^^]; Compiled=%s; Err=%v\n", regexString, err)
+	}
+
+	// Negated classes are negated sets in Python, which can't be put inside another set
+	_, err = NewRegexl("select none_of_chars(not_digit())").CompileString(Dialect_Python)
+	if err == nil {
+		t.Errorf("Passing not_digit to none_of_chars should fail in Python\n")
+	}
+
+	if _, err := NewRegexl("select none_of_chars(any_char())").CompileString(Dialect_Go); err == nil {
+		t.Errorf("Passing any_char to none_of_chars should fail\n")
+	}
+}
+
+func TestQuantifiers(t *testing.T) {
+
+	query := `
+	set_options({
+		case_sensitive: true,
+	})
+	select optional('-') + exactly(digit(), 3) + at_least('ab', 2) + at_most(capture('x'), 4) + count_between('ab', 1, 2) + optional(one_plus_of('c'))
+	`
+
+	testCases := []struct {
+		dialect       string
+		expectedRegex string
+	}{
+		{
+			dialect:       Dialect_Go,
+			expectedRegex: `(?)-?\d{3}(?:ab){2,}(x){0,4}(?:ab){1,2}(?:(?:c)+)?`,
+		},
+		{
+			dialect:       Dialect_JavaScript,
+			expectedRegex: `/-?\d{3}(?:ab){2,}(x){0,4}(?:ab){1,2}(?:(?:c)+)?/u`,
+		},
+		{
+			dialect:       Dialect_Python,
+			expectedRegex: `\-?[0-9]{3}(?:ab){2,}(x){0,4}(?:ab){1,2}(?:(?:c)+)?`,
+		},
+		{
+			dialect:       Dialect_PCRE2,
+			expectedRegex: `\-?\d{3}(?:ab){2,}(x){0,4}(?:ab){1,2}(?:(?:c)+)?`,
+		},
+		{
+			dialect:       Dialect_Rust,
+			expectedRegex: `-?[[:digit:]]{3}(?:ab){2,}(x){0,4}(?:ab){1,2}(?:(?:c)+)?`,
+		},
+		{
+			dialect:       Dialect_POSIX_ERE,
+			expectedRegex: `-?[[:digit:]]{3}(ab){2,}(x){0,4}(ab){1,2}((c)+)?`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+
+	re := NewRegexl(query).MustCompile().CompiledRegexp
+	if !re.MatchString(`-123ababxxabcc`) || !re.MatchString(`123ababab`) || re.MatchString(`12ababab`) || re.MatchString(`123abxab`) {
+		t.Errorf("Quantifiers don't match the right text. Regex=%s\n", re.String())
+	}
+
+	invalidCounts := []struct {
+		desc  string
+		query string
+	}{
+		{
+			desc:  "Negative count",
+			query: `select exactly('a', -1)`,
+		},
+		{
+			desc:  "Count above the repeat limit",
+			query: `select at_least('a', 1001)`,
+		},
+		{
+			desc:  "Maximum less than minimum",
+			query: `select count_between('a', 3, 2)`,
+		},
+		{
+			desc:  "String count",
+			query: `select at_most('a', '3')`,
+		},
+		{
+			desc:  "Float count",
+			query: `select exactly('a', 2.5)`,
+		},
+	}
+
+	for _, tc := range invalidCounts {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			if _, err := NewRegexl(tc.query).CompileString(Dialect_Go); err == nil {
+				t.Errorf("Expected compilation to fail. Query=%s\n", tc.query)
+			}
+		})
+	}
+}
+
+func TestLazyQuantifiers(t *testing.T) {
+
+	query := `select '"' + lazy_zero_plus_of(any_char()) + '"' + lazy_count_between(digit(), 1, 3) + one_plus_of('b') + any_chars()`
+	ungreedyQuery := "set_options({ungreedy: true})\n" + query
+
+	testCases := []struct {
+		dialect               string
+		expectedRegex         string
+		expectedUngreedyRegex string
+	}{
+		{
+			dialect:               Dialect_Go,
+			expectedRegex:         `(?i)"(?:.)*?"\d{1,3}?(?:b)+.*`,
+			expectedUngreedyRegex: `(?iU)"(?:.)*?"\d{1,3}?(?:b)+.*`,
+		},
+		{
+			dialect:               Dialect_JavaScript,
+			expectedRegex:         `/"(?:[^\n])*?"\d{1,3}?(?:b)+[^\n]*/iu`,
+			expectedUngreedyRegex: `/"(?:[^\n])*"\d{1,3}(?:b)+?[^\n]*?/iu`,
+		},
+		{
+			dialect:               Dialect_Python,
+			expectedRegex:         `(?i)"(?:.)*?"[0-9]{1,3}?(?:b)+.*`,
+			expectedUngreedyRegex: `(?i)"(?:.)*"[0-9]{1,3}(?:b)+?.*?`,
+		},
+		{
+			dialect:               Dialect_PCRE2,
+			expectedRegex:         `(?i)"(?:.)*?"\d{1,3}?(?:b)+.*`,
+			expectedUngreedyRegex: `(?iU)"(?:.)*?"\d{1,3}?(?:b)+.*`,
+		},
+		{
+			dialect:               Dialect_Rust,
+			expectedRegex:         `(?i)"(?:.)*?"[[:digit:]]{1,3}?(?:b)+.*`,
+			expectedUngreedyRegex: `(?iU)"(?:.)*?"[[:digit:]]{1,3}?(?:b)+.*`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+
+			regexString, err = NewRegexl(ungreedyQuery).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation with the ungreedy option failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedUngreedyRegex {
+				t.Errorf("Compiled ungreedy regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedUngreedyRegex, regexString)
+			}
+		})
+	}
+
+	// Only the first quoted string must be matched
+	firstQuoted := NewRegexl(`select '"' + lazy_zero_plus_of(any_char()) + '"'`).MustCompile().CompiledRegexp
+	if match := firstQuoted.FindString(`say "hi" and "bye"`); match != `"hi"` {
+		t.Errorf("Lazy quantifier matched the wrong text. Expected=\"hi\"; Matched=%s\n", match)
+	}
+
+	ungreedy := NewRegexl(`set_options({ungreedy: true}) select '"' + zero_plus_of(any_char()) + '"'`).MustCompile().CompiledRegexp
+	if match := ungreedy.FindString(`say "hi" and "bye"`); match != `"hi"` {
+		t.Errorf("Greedy quantifier with the ungreedy option matched the wrong text. Expected=\"hi\"; Matched=%s\n", match)
+	}
+
+	// POSIX ERE has no lazy quantifiers
+	for _, q := range []string{query, `set_options({ungreedy: true}) select 'a'`} {
+
+		if _, err := NewRegexl(q).CompileString(Dialect_POSIX_ERE); err == nil {
+			t.Errorf("Lazy quantifiers should fail with POSIX ERE. Query=%s\n", q)
+		}
+	}
+}
+
+func TestAlternation(t *testing.T) {
+
+	query := `select starts_with('GET' or 'POST') + ' /' + any_strings_of('a', 'b') + 'x' | 'y'`
+
+	testCases := []struct {
+		dialect       string
+		expectedRegex string
+	}{
+		{
+			dialect:       Dialect_Go,
+			expectedRegex: `(?i)^(?:GET|POST) /(?:a|b)x|y`,
+		},
+		{
+			dialect:       Dialect_JavaScript,
+			expectedRegex: `/^(?:GET|POST) \/(?:a|b)x|y/iu`,
+		},
+		{
+			dialect:       Dialect_Python,
+			expectedRegex: `(?i)^(?:GET|POST)\ /(?:a|b)x|y`,
+		},
+		{
+			dialect:       Dialect_PCRE2,
+			expectedRegex: `(?i)^(?:GET|POST) \/(?:a|b)x|y`,
+		},
+		{
+			dialect:       Dialect_Rust,
+			expectedRegex: `(?i)^(?:GET|POST) /(?:a|b)x|y`,
+		},
+		{
+			dialect:       Dialect_POSIX_ERE,
+			expectedRegex: `^([Gg][Ee][Tt]|[Pp][Oo][Ss][Tt]) /([aA]|[bB])[xX]|[yY]`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+
+	// '+' binds tighter than 'or', so this is ('a' + 'b') or 'c'
+	ast, err := NewRegexl(`select 'a' + 'b' or 'c'`).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed. Err=%v\n", err)
+	}
+
+	or, ok := ast.Nodes[0].(*SelectStmt).Es[0].(*BinaryExpr)
+	if !ok || or.Type != TokenType_Or {
+		t.Fatalf("Expected an 'or' at the top of the select, but found %s\n", describeNode(ast.Nodes[0].(*SelectStmt).Es[0]))
+	}
+
+	if plus, ok := or.Lhs.(*BinaryExpr); !ok || plus.Type != TokenType_Plus {
+		t.Errorf("Expected a '+' on the left side of 'or', but found %s\n", describeNode(or.Lhs))
+	}
+
+	re := NewRegexl(`select 'x' + any_strings_of('a' or 'b' + 'c') + 'y'`).MustCompile()
+	if !re.CompiledRegexp.MatchString("xay") || !re.CompiledRegexp.MatchString("xbcy") || re.CompiledRegexp.MatchString("xa") {
+		t.Errorf("Alternation matches the wrong text. Regex=%s\n", re.CompiledRegexp.String())
+	}
+}
+
+func TestLineOptions(t *testing.T) {
+
+	query := `select text_starts_with('a') + line_starts_with('b') + any_chars() + line_ends_with('c') + text_ends_with('d') + ends_with('e')`
+	multilineQuery := "set_options({multiline: true, dot_matches_newline: true})\n" + query
+
+	testCases := []struct {
+		dialect                string
+		expectedRegex          string
+		expectedMultilineRegex string
+	}{
+		{
+			dialect:                Dialect_Go,
+			expectedRegex:          `(?i)\Aa(?m:^)b.*c(?m:$)d\ze$`,
+			expectedMultilineRegex: `(?ims)\Aa^b.*c$d\ze$`,
+		},
+		{
+			dialect:                Dialect_JavaScript,
+			expectedRegex:          `/(?<![\s\S])a(?<![^\n])b[^\n]*c(?![^\n])d(?![\s\S])e$/iu`,
+			expectedMultilineRegex: `/(?<![\s\S])a^b.*c$d(?![\s\S])e$/imsu`,
+		},
+		{
+			dialect:                Dialect_Python,
+			expectedRegex:          `(?i)\Aa(?m:^)b.*c(?m:$)d\Ze\Z`,
+			expectedMultilineRegex: `(?ims)\Aa^b.*c$d\Ze$`,
+		},
+		{
+			dialect:                Dialect_PCRE2,
+			expectedRegex:          `(?i)\Aa(?m:^)b.*c(?m:$)d\ze\z`,
+			expectedMultilineRegex: `(?ims)\Aa^b.*c$d\ze$`,
+		},
+		{
+			dialect:                Dialect_Rust,
+			expectedRegex:          `(?i)\Aa(?m:^)b.*c(?m:$)d\ze$`,
+			expectedMultilineRegex: `(?ims)\Aa^b.*c$d\ze$`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+
+			regexString, err = NewRegexl(multilineQuery).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation with line options failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedMultilineRegex {
+				t.Errorf("Compiled regex with line options does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedMultilineRegex, regexString)
+			}
+		})
+	}
+
+	text := "start\nERROR disk full\nend"
+
+	lineRe := NewRegexl(`select line_starts_with('error') + any_chars() + line_ends_with('full')`).MustCompile().CompiledRegexp
+	if match := lineRe.FindString(text); match != "ERROR disk full" {
+		t.Errorf("Line anchors matched the wrong text. Expected=ERROR disk full; Matched=%q\n", match)
+	}
+
+	textRe := NewRegexl(`set_options({multiline: true}) select text_starts_with('error')`).MustCompile().CompiledRegexp
+	if textRe.MatchString(text) {
+		t.Errorf("Text anchors must not match at lines with the multiline option. Regex=%s\n", textRe.String())
+	}
+
+	multilineRe := NewRegexl(`set_options({multiline: true}) select starts_with('error') + any_chars() + ends_with('')`).MustCompile().CompiledRegexp
+	if match := multilineRe.FindString(text); match != "ERROR disk full" {
+		t.Errorf("starts_with and ends_with with the multiline option matched the wrong text. Expected=ERROR disk full; Matched=%q\n", match)
+	}
+
+	dotAllRe := NewRegexl(`set_options({dot_matches_newline: true}) select 'start' + any_chars() + 'end'`).MustCompile().CompiledRegexp
+	if !dotAllRe.MatchString(text) {
+		t.Errorf("any_chars with the dot_matches_newline option must match new lines. Regex=%s\n", dotAllRe.String())
+	}
+
+	// POSIX ERE has no flags
+	for _, option := range []string{"multiline", "dot_matches_newline"} {
+
+		q := "set_options({" + option + ": true}) select 'a'"
+		if _, err := NewRegexl(q).CompileString(Dialect_POSIX_ERE); err == nil {
+			t.Errorf("Option '%s' should fail with POSIX ERE\n", option)
+		}
+	}
+}
+
+func TestScopedOptions(t *testing.T) {
+
+	// A case insensitive keyword followed by a case sensitive identifier
+	query := `select starts_with('let') + ' ' + with_options({case_sensitive: true}, one_plus_of(any_chars_of(from_to('a', 'z'))))`
+	nestedQuery := `set_options({case_sensitive: true, multiline: true})
+select with_options({case_sensitive: false, multiline: false, dot_matches_newline: true}, 'a' or with_options({case_sensitive: true}, 'b')) + with_options({}, 'c')`
+
+	testCases := []struct {
+		dialect             string
+		expectedRegex       string
+		expectedNestedRegex string
+	}{
+		{
+			dialect:             Dialect_Go,
+			expectedRegex:       `(?i)^let (?-i:(?:[a-z])+)`,
+			expectedNestedRegex: `(?m)(?is-m:a|(?-i:b))(?:c)`,
+		},
+		{
+			dialect:             Dialect_JavaScript,
+			expectedRegex:       `/^let (?-i:(?:[a-z])+)/iu`,
+			expectedNestedRegex: `/(?is-m:a|(?-i:b))(?:c)/mu`,
+		},
+		{
+			dialect:             Dialect_Python,
+			expectedRegex:       `(?i)^let\ (?-i:(?:[a-z])+)`,
+			expectedNestedRegex: `(?m)(?is-m:a|(?-i:b))(?:c)`,
+		},
+		{
+			dialect:             Dialect_PCRE2,
+			expectedRegex:       `(?i)^let (?-i:(?:[a-z])+)`,
+			expectedNestedRegex: `(?m)(?is-m:a|(?-i:b))(?:c)`,
+		},
+		{
+			dialect:             Dialect_Rust,
+			expectedRegex:       `(?i)^let (?-i:(?:[a-z])+)`,
+			expectedNestedRegex: `(?m)(?is-m:a|(?-i:b))(?:c)`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.dialect, func(t *testing.T) {
+
+			regexString, err := NewRegexl(query).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedRegex {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+
+			regexString, err = NewRegexl(nestedQuery).CompileString(tc.dialect)
+			if err != nil {
+				t.Fatalf("Compilation of nested scopes failed. Err=%v\n", err)
+			}
+
+			if regexString != tc.expectedNestedRegex {
+				t.Errorf("Compiled regex of nested scopes does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedNestedRegex, regexString)
+			}
+		})
+	}
+
+	re := NewRegexl(query).MustCompile().CompiledRegexp
+	for text, shouldMatch := range map[string]bool{"LET value": true, "let value": true, "let Value": false} {
+
+		if re.MatchString(text) != shouldMatch {
+			t.Errorf("Scoped case sensitivity matched wrongly. Text=%q; ShouldMatch=%v; Regex=%s\n", text, shouldMatch, re.String())
+		}
+	}
+
+	// Options emulated by how the regex is written only apply inside the scope
+	regexString, err := NewRegexl(`select with_options({ungreedy: true}, zero_plus_of('a')) + zero_plus_of('b')`).CompileString(Dialect_JavaScript)
+	if expected := `/(?:(?:a)*?)(?:b)*/iu`; err != nil || regexString != expected {
+		t.Errorf("Scoped ungreedy option was applied wrongly. Expected=%s; Compiled=%s; Err=%v\n", expected, regexString, err)
+	}
+
+	regexString, err = NewRegexl(query).CompileString(Dialect_POSIX_ERE)
+	if expected := `^[lL][eE][tT] (([a-z])+)`; err != nil || regexString != expected {
+		t.Errorf("Scoped case sensitivity was applied wrongly with POSIX ERE. Expected=%s; Compiled=%s; Err=%v\n", expected, regexString, err)
+	}
+
+	// find_all_matches applies to the whole query
+	if err := NewRegexl(`select with_options({find_all_matches: true}, 'a')`).Compile(); err == nil {
+		t.Errorf("Option 'find_all_matches' should fail in with_options\n")
+	}
+}
+
+func TestBackendRegistry(t *testing.T) {
+
+	if !slices.Contains(Dialects(), Dialect_Go) {
+		t.Fatalf("Go backend is not registered. Dialects=%v\n", Dialects())
+	}
+
+	if _, err := NewBackend("not-a-dialect"); err == nil {
+		t.Fatalf("Creating a backend of an unregistered dialect should have thrown an error but didn't\n")
+	}
+
+	rl := NewRegexl(`select starts_with('hello')`)
+	regexString, err := rl.CompileString(Dialect_Go)
+	if err != nil {
+		t.Fatalf("Compiling to string failed. Err=%v\n", err)
+	}
+
+	if regexString != "(?i)^hello" || rl.CompiledRegexp != nil || rl.CompiledString != "" {
+		t.Fatalf("CompileString returned wrong regex or changed the Regexl object. Regex=%s; Regexl=%+v\n", regexString, rl)
+	}
+
+	RegisterBackend("test-upper", func() Backend { return &upperCaseTestBackend{} })
+	err = rl.CompileFor("test-upper")
+	if err != nil {
+		t.Fatalf("Compiling for a custom dialect failed. Err=%v\n", err)
+	}
+
+	if rl.CompiledString != "(?I)^HELLO" || rl.Dialect != "test-upper" || len(rl.Diagnostics) != 1 || rl.CompiledRegexp != nil {
+		t.Fatalf("Compiling for a custom dialect produced the wrong output. Regexl=%+v\n", rl)
+	}
+
+	err = rl.CompileFor(Dialect_Go)
+	if err != nil {
+		t.Fatalf("Compiling for Go failed. Err=%v\n", err)
+	}
+
+	if rl.CompiledRegexp == nil || rl.CompiledRegexp.String() != rl.CompiledString || len(rl.Diagnostics) != 0 {
+		t.Fatalf("Compiling for Go produced the wrong output. Regexl=%+v\n", rl)
+	}
+
+	optsRl := NewRegexl(`set_options({find_all_matches: true}) select 'a'`)
+	if err := optsRl.Compile(); err != nil || !optsRl.Opts.FindAllMatches {
+		t.Fatalf("Compiling for Go must set the options of the query. Opts=%+v; Err=%v\n", optsRl.Opts, err)
+	}
+
+	if err := optsRl.CompileFor(Dialect_Python); err != nil || optsRl.Opts.FindAllMatches {
+		t.Fatalf("Compiling for another dialect must clear the options. Opts=%+v; Err=%v\n", optsRl.Opts, err)
+	}
+
+	// The Go regex of the earlier compile must not be kept
+	err = rl.CompileFor(Dialect_JavaScript)
+	if err != nil {
+		t.Fatalf("Compiling for JavaScript failed. Err=%v\n", err)
+	}
+
+	if rl.CompiledRegexp != nil || rl.CompiledString != "/^hello/iu" || rl.Dialect != Dialect_JavaScript {
+		t.Fatalf("Compiling for JavaScript after Go produced the wrong output. Regexl=%+v\n", rl)
+	}
+}
+
+func TestJsBackend(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedRegex string
+		shouldError   bool
+	}{
+		{
+			desc:          "Simplest",
+			query:         `select 'friend'`,
+			expectedRegex: "/friend/iu",
+		},
+		{
+			desc: "Options",
+			query: `
+			set_options({
+				find_all_matches: true,
+				case_sensitive: true,
+			})
+			select starts_with('Hello') + any_chars() + 'a/b'
+			`,
+			expectedRegex: `/^Hello[^\n]*a\/b/gu`,
+		},
+		{
+			desc: "Named capture",
+			query: `
+			select capture_as('year', count_between(any_chars_of(from_to(0, 9)), 4, 4)) + capture(one_plus_of('.'))
+			`,
+			expectedRegex: `/(?<year>[0-9]{4,4})((?:\.)+)/iu`,
+		},
+		{
+			desc:          "Empty",
+			query:         `select ''`,
+			expectedRegex: "/(?:)/iu",
+		},
+		{
+			desc:          "Escaping",
+			query:         `select 'a|b/c$' + any_chars_of('/]^-.')`,
+			expectedRegex: `/a\|b\/c\$[\/\]\^\-.]/iu`,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Count above the repeat limit",
+			query:       `select count_between('a', 1, 1001)`,
+			shouldError: true,
+		},
+		{
+			desc:        "Invalid capture_as: duplicate name",
+			query:       `select capture_as('word', 'a') + capture_as('word', 'b')`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			regexString, err := NewRegexl(tc.query).CompileString(Dialect_JavaScript)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Compilation should have thrown an error but didn't. Query=%s\n", tc.query)
+				return
+			}
+
+			if tc.expectedRegex != regexString {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+}
+
+// TestJsAgreesWithGo checks that the JavaScript backend accepts exactly the queries the Go backend accepts, using the queries of all test cases in this file
+func TestJsAgreesWithGo(t *testing.T) {
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "regexl_test.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Parsing the test file failed. Err=%v\n", err)
+	}
+
+	queries := make([]string, 0, 256)
+	ast.Inspect(file, func(n ast.Node) bool {
+
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+
+		key, ok := kv.Key.(*ast.Ident)
+		lit, isLit := kv.Value.(*ast.BasicLit)
+		if !ok || key.Name != "query" || !isLit || lit.Kind != token.STRING {
+			return true
+		}
+
+		query, err := strconv.Unquote(lit.Value)
+		if err == nil {
+			queries = append(queries, query)
+		}
+
+		return true
+	})
+
+	if len(queries) < 50 {
+		t.Fatalf("Expected to find the queries of the test cases but found only %d\n", len(queries))
+	}
+
+	for _, query := range queries {
+
+		_, goErr := NewRegexl(query).CompileString(Dialect_Go)
+		_, jsErr := NewRegexl(query).CompileString(Dialect_JavaScript)
+		if (goErr == nil) != (jsErr == nil) {
+			t.Errorf("Go and JavaScript disagree on whether the query is valid. GoErr=%v; JsErr=%v; Query=%s\n", goErr, jsErr, query)
+		}
+	}
+}
+
+func TestPythonBackend(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedRegex string
+		shouldError   bool
+	}{
+		{
+			desc:          "Simplest",
+			query:         `select 'friend'`,
+			expectedRegex: "(?i)friend",
+		},
+		{
+			desc: "Escaping and anchors",
+			query: `
+			set_options({
+				case_sensitive: true,
+			})
+			select starts_with('Hello there, ') + one_plus_of(any_chars_of(from_to('A', 'Z'), '.!-')) + ends_with('a+b')
+			`,
+			expectedRegex: `^Hello\ there,\ (?:[A-Z.!\-])+a\+b\Z`,
+		},
+		{
+			desc:          "Escaping in character sets",
+			query:         `select any_chars_of(']^-&&~~||. ')`,
+			expectedRegex: `(?i)[\]\^\-\&\&\~\~\|\|. ]`,
+		},
+		{
+			desc: "Named capture",
+			query: `
+			select capture_as('year', count_between(any_chars_of(from_to(0, 9)), 4, 4))
+			`,
+			expectedRegex: `(?i)(?P<year>[0-9]{4,4})`,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Invalid capture_as: duplicate name",
+			query:       `select capture_as('word', 'a') + capture_as('word', 'b')`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			regexString, err := NewRegexl(tc.query).CompileString(Dialect_Python)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Compilation should have thrown an error but didn't. Query=%s\n", tc.query)
+				return
+			}
+
+			if tc.expectedRegex != regexString {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+}
+
+func TestLookbehindWidth(t *testing.T) {
+
+	testCases := []struct {
+		desc    string
+		dialect string
+		query   string
+		// expectedErrAt is the lookbehind pattern the error is about. Empty means the query is valid
+		expectedErrAt string
+	}{
+		{
+			desc:    "Python fixed width",
+			dialect: Dialect_Python,
+			query:   `select preceded_by(exactly('ab', 3) + digit()) + not_preceded_by(capture_as('n', 'ab' or 'cd')) + 'x'`,
+		},
+		{
+			desc:          "Python unbounded",
+			dialect:       Dialect_Python,
+			query:         `select preceded_by(one_plus_of('a')) + 'x'`,
+			expectedErrAt: `one_plus_of('a')`,
+		},
+		{
+			desc:          "Python alternatives of different lengths",
+			dialect:       Dialect_Python,
+			query:         `select not_preceded_by('a' or 'bc') + 'x'`,
+			expectedErrAt: `'a' or 'bc'`,
+		},
+		{
+			desc:          "Python optional",
+			dialect:       Dialect_Python,
+			query:         `select 'x' + preceded_by('a' + optional('b'))`,
+			expectedErrAt: `'a' + optional('b')`,
+		},
+		{
+			desc:    "PCRE2 bounded",
+			dialect: Dialect_PCRE2,
+			query:   `select not_preceded_by('a' or 'bc') + preceded_by(at_most('a', 255)) + preceded_by(exactly('a', 300)) + 'x'`,
+		},
+		{
+			desc:          "PCRE2 unbounded",
+			dialect:       Dialect_PCRE2,
+			query:         `select preceded_by(one_plus_of('a')) + 'x'`,
+			expectedErrAt: `one_plus_of('a')`,
+		},
+		{
+			desc:          "PCRE2 above the limit",
+			dialect:       Dialect_PCRE2,
+			query:         `select 'x' + not_preceded_by('a' + at_most('b', 255))`,
+			expectedErrAt: `'a' + at_most('b', 255)`,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			_, err := NewRegexl(tc.query).CompileString(tc.dialect)
+			if tc.expectedErrAt == "" {
+
+				if err != nil {
+					t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				}
+
+				return
+			}
+
+			var backendErr *BackendError
+			if !errors.As(err, &backendErr) {
+				t.Fatalf("Compilation should have returned a BackendError. Err=%v; Query=%s\n", err, tc.query)
+			}
+
+			if errText := tc.query[backendErr.Pos:backendErr.End]; errText != tc.expectedErrAt {
+				t.Errorf("BackendError is about the wrong part of the query. Expected=%s; Found=%s\n", tc.expectedErrAt, errText)
+			}
+		})
+	}
+
+	// Atomic groups and possessive quantifiers need Python 3.11
+	rl := NewRegexl(`select atomic('a') + possessive_one_plus_of('b')`)
+	err := rl.CompileFor(Dialect_Python)
+	if err != nil || len(rl.Diagnostics) != 2 || !strings.Contains(rl.Diagnostics[0].Msg, "Python 3.11") {
+		t.Errorf("Atomic groups and possessive quantifiers should be noted in Python. Diagnostics=%v; Err=%v\n", rl.Diagnostics, err)
+	}
+
+	ast, err := NewRegexl(`select atomic('a')`).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed. Err=%v\n", err)
+	}
+
+	_, _, diags, err := (&PythonBackend{}).AstToPythonRegex(ast)
+	if err != nil || len(diags) != 1 {
+		t.Errorf("AstToPythonRegex should return the diagnostics. Diagnostics=%v; Err=%v\n", diags, err)
+	}
+
+	// Alternatives of different lengths need PCRE2 10.43, while alternatives that each have a fixed length don't
+	rl = NewRegexl(`select preceded_by('a' or 'bc') + preceded_by('a' + optional('b')) + 'x'`)
+	err = rl.CompileFor(Dialect_PCRE2)
+	if err != nil || len(rl.Diagnostics) != 1 || !strings.Contains(rl.Diagnostics[0].Msg, "PCRE2 10.43") {
+		t.Errorf("Variable length lookbehinds should be noted in PCRE2. Diagnostics=%v; Err=%v\n", rl.Diagnostics, err)
+	}
+}
+
+func TestPcre2Backend(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedRegex string
+		shouldError   bool
+	}{
+		{
+			desc: "Escaping and anchors",
+			query: `
+			set_options({
+				case_sensitive: true,
+			})
+			select starts_with('a+b') + ends_with('c/d')
+			`,
+			expectedRegex: `^a\+bc\/d\z`,
+		},
+		{
+			desc:          "Escaping in character sets",
+			query:         `select any_chars_of('/]^-.$')`,
+			expectedRegex: `(?i)[\/\]\^\-.$]`,
+		},
+		{
+			desc: "Lookarounds",
+			query: `
+			select preceded_by('$') + one_plus_of(any_chars_of(from_to(0, 9))) + not_followed_by('%')
+			`,
+			expectedRegex: `(?i)(?<=\$)(?:[0-9])+(?!%)`,
+		},
+		{
+			desc: "Negated lookbehind and lookahead",
+			query: `
+			select not_preceded_by('-') + 'x' + followed_by('y')
+			`,
+			expectedRegex: `(?i)(?<!\-)x(?=y)`,
+		},
+		{
+			desc: "Backreference",
+			query: `
+			select capture_as('quote', any_chars_of('"#')) + any_chars() + same_as_capture('quote')
+			`,
+			expectedRegex: `(?i)(?<quote>["#]).*\k<quote>`,
+		},
+		{
+			desc: "Atomic and possessive",
+			query: `
+			select atomic('a' + possessive_zero_plus_of('b')) + possessive_one_plus_of('c') + possessive_count_between('d', 1, 2)
+			`,
+			expectedRegex: `(?i)(?>a(?:b)*+)(?:c)++d{1,2}+`,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Backreference to unknown capture",
+			query:       `select same_as_capture('word') + capture_as('word', 'a')`,
+			shouldError: true,
+		},
+		{
+			desc:        "Possessive with wrong arguments",
+			query:       `select possessive_count_between('d', 1)`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			regexString, err := NewRegexl(tc.query).CompileString(Dialect_PCRE2)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Compilation should have thrown an error but didn't. Query=%s\n", tc.query)
+				return
+			}
+
+			if tc.expectedRegex != regexString {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+}
+
+func TestUnsupportedFuncs(t *testing.T) {
+
+	queries := []string{
+		`select 'a' + followed_by('b')`,
+		`select not_preceded_by('b') + 'a'`,
+		`select capture_as('word', 'a') + same_as_capture('word')`,
+		`select atomic('a')`,
+		`select possessive_one_plus_of('a')`,
+	}
+
+	for _, query := range queries {
+
+		for _, dialect := range []string{Dialect_Go, Dialect_JavaScript} {
+
+			_, err := NewRegexl(query).CompileString(dialect)
+
+			var backendErr *BackendError
+			if !errors.As(err, &backendErr) {
+				t.Errorf("Compiling a function unsupported by the dialect should have returned a BackendError. Dialect=%s; Err=%v; Query=%s\n", dialect, err, query)
+				continue
+			}
+
+			if backendErr.Pos <= 0 || !strings.Contains(backendErr.Error(), "'"+dialect+"'") {
+				t.Errorf("BackendError has the wrong position or message. Dialect=%s; Err=%v; Query=%s\n", dialect, err, query)
+			}
+		}
+	}
+}
+
+func TestPosixBackend(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedRegex string
+		expectedDiags int
+		shouldError   bool
+	}{
+		{
+			desc: "Email query",
+			query: `
+			select
+				one_plus_of(any_chars_of(from_to('A', 'Z'), from_to(0, 9), '._%+-')) +
+				'@' +
+				one_plus_of(any_chars_of(from_to('A', 'Z'), from_to(0, 9), '.-')) +
+				'.' +
+				count_between(any_chars_of(from_to('A', 'Z')), 2, 10)
+			`,
+			expectedRegex: `([A-Z[:digit:]._%+a-z-])+@([A-Z[:digit:].a-z-])+\.[A-Za-z]{2,10}`,
+			expectedDiags: 2,
+		},
+		{
+			desc: "Case insensitive literals",
+			query: `
+			select starts_with('Hi (1)') + ends_with('k')
+			`,
+			expectedRegex: `^[Hh][iI] \(1\)[kK]$`,
+		},
+		{
+			desc: "Special characters in brackets",
+			query: `
+			set_options({
+				case_sensitive: true,
+			})
+			select any_chars_of(']^-[\\a') + any_chars_of('^') + any_chars_of('-^')
+			`,
+			expectedRegex: `[]\a[^-]\^[-^]`,
+		},
+		{
+			desc: "Captures",
+			query: `
+			set_options({
+				case_sensitive: true,
+			})
+			select capture_as('year', count_between(any_chars_of(from_to(0, 9)), 4, 4)) + capture('x')
+			`,
+			expectedRegex: `([[:digit:]]{4,4})(x)`,
+			expectedDiags: 1,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Lookaround",
+			query:       `select 'a' + followed_by('b')`,
+			shouldError: true,
+		},
+		{
+			desc:        "Range with a special character",
+			query:       `select any_chars_of(from_to('-', 'a'))`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			rl := NewRegexl(tc.query)
+			err := rl.CompileFor(Dialect_POSIX_ERE)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Compilation should have thrown an error but didn't. Query=%s\n", tc.query)
+				return
+			}
+
+			if tc.expectedRegex != rl.CompiledString {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, rl.CompiledString)
+			}
+
+			if tc.expectedDiags != len(rl.Diagnostics) {
+				t.Errorf("Expected %d diagnostics but found %d. Diagnostics=%v\n", tc.expectedDiags, len(rl.Diagnostics), rl.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestRustBackend(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedRegex string
+		shouldError   bool
+	}{
+		{
+			desc:          "Simplest",
+			query:         `select 'friend'`,
+			expectedRegex: "(?i)friend",
+		},
+		{
+			desc: "Case sensitive with set operators",
+			query: `
+			set_options({
+				case_sensitive: true,
+			})
+			select capture_as('op', one_plus_of(any_chars_of(from_to('a', 'z'), '&~-'))) + ends_with('.')
+			`,
+			expectedRegex: `(?P<op>(?:[a-z\&\~\-])+)\.$`,
+		},
+		{
+			desc: "Named capture",
+			query: `
+			select capture_as('year', count_between(any_chars_of(from_to(0, 9)), 4, 4)) + capture(one_plus_of('.'))
+			`,
+			expectedRegex: `(?i)(?P<year>[0-9]{4,4})((?:\.)+)`,
+		},
+		{
+			desc:          "Empty",
+			query:         `select ''`,
+			expectedRegex: "(?i)",
+		},
+		{
+			desc:          "Escaping",
+			query:         `select 'a|b/c$' + any_chars_of('/]^-.')`,
+			expectedRegex: `(?i)a\|b/c\$[/\]\^\-.]`,
+		},
+		{
+			desc:          "Set operators in character sets",
+			query:         `select any_chars_of(']^-&&~~||. ')`,
+			expectedRegex: `(?i)[\]\^\-\&\&\~\~||. ]`,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Lookaround",
+			query:       `select 'a' + followed_by('b')`,
+			shouldError: true,
+		},
+		{
+			desc:        "Backreference",
+			query:       `select capture_as('quote', any_chars_of('"#')) + any_chars() + same_as_capture('quote')`,
+			shouldError: true,
+		},
+		{
+			desc:        "Atomic group",
+			query:       `select atomic('a' + 'b')`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			regexString, err := NewRegexl(tc.query).CompileString(Dialect_Rust)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Compilation failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Compilation should have thrown an error but didn't. Query=%s\n", tc.query)
+				return
+			}
+
+			if tc.expectedRegex != regexString {
+				t.Errorf("Compiled regex does not equal expected regex. Expected=%s; Compiled=%s\n", tc.expectedRegex, regexString)
+			}
+		})
+	}
+}
+
+func TestDecompile(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		regex         string
+		expectedQuery string
+		shouldError   bool
+	}{
+		{
+			desc:          "Simplest",
+			regex:         "(?i)friend",
+			expectedQuery: "select 'friend'\n",
+		},
+		{
+			desc:          "Case sensitive anchors",
+			regex:         "^Golang$",
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect ends_with(starts_with('Golang'))\n",
+		},
+		{
+			desc:  "Email query",
+			regex: `(?i)(?:[A-Z0-9\._%+-])+@(?:[A-Z0-9\.-])+\.[A-Z]{2,10}`,
+			expectedQuery: `select
+	one_plus_of(any_chars_of(from_to('0', '9'), from_to('a', 'z'), '%+-._')) +
+	'@' +
+	one_plus_of(any_chars_of(from_to('0', '9'), from_to('a', 'z'), '-.')) +
+	'.' +
+	count_between(any_chars_of(from_to('a', 'z')), 2, 10)
+`,
+		},
+		{
+			desc:          "Captures without letters",
+			regex:         `(?P<year>[0-9]{4})-([0-9]{2})`,
+			expectedQuery: "select\n\tcapture_as('year', exactly(any_chars_of(from_to('0', '9')), 4)) +\n\t'-' +\n\tcapture(exactly(any_chars_of(from_to('0', '9')), 2))\n",
+		},
+		{
+			desc:          "Alternation and repeats",
+			regex:         `(?i)hello|byex*.*`,
+			expectedQuery: "select any_strings_of('hello', 'bye' + zero_plus_of('x') + any_chars())\n",
+		},
+		{
+			desc:          "Mixed case sensitivity",
+			regex:         `(?i)let (?-i:[a-z])`,
+			expectedQuery: "select\n\t'let ' +\n\twith_options(\n\t\t{\n\t\t\tcase_sensitive: true,\n\t\t},\n\t\tany_chars_of(from_to('a', 'z'))\n\t)\n",
+		},
+		{
+			desc:          "Line and text anchors",
+			regex:         `(?im)^error.*$\z`,
+			expectedQuery: "select line_starts_with('error') + text_ends_with(line_ends_with(any_chars()))\n",
+		},
+		{
+			desc:          "Grouped alternation",
+			regex:         `x(?:ab|cd)`,
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect 'x' + any_strings_of('ab', 'cd')\n",
+		},
+		{
+			desc:  "Lazy quantifiers",
+			regex: `(?i)"(.*?)"x??`,
+			expectedQuery: `select
+	'"' +
+	capture(lazy_zero_plus_of(any_char())) +
+	'"' +
+	lazy_count_between('x', 0, 1)
+`,
+		},
+		{
+			desc:          "Quotes, backslashes and tabs",
+			regex:         `it's\\\t`,
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect 'it\\'s\\\\\\t'\n",
+		},
+		{
+			desc:          "Negated range",
+			regex:         `[^a-z]`,
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect none_of_chars(from_to('a', 'z'))\n",
+		},
+		{
+			desc:          "Negated character class",
+			regex:         `[^\d]+`,
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect one_plus_of(none_of_chars(from_to('0', '9')))\n",
+		},
+		{
+			desc:          "Everything up to the next quote",
+			regex:         `(?i)"[^"]*"`,
+			expectedQuery: "select '\"' + zero_plus_of(none_of_chars('\"')) + '\"'\n",
+		},
+		{
+			desc:          "Any character including new lines",
+			regex:         `(?s)a.b`,
+			expectedQuery: "set_options({\n\tcase_sensitive: true,\n})\n\nselect\n\t'a' +\n\twith_options(\n\t\t{\n\t\t\tdot_matches_newline: true,\n\t\t},\n\t\tany_char()\n\t) +\n\t'b'\n",
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Invalid regex",
+			regex:       `a(`,
+			shouldError: true,
+		},
+		{
+			desc:        "Word boundary",
+			regex:       `\bword`,
+			shouldError: true,
+		},
+		{
+			desc:        "Lazy repeat without a maximum",
+			regex:       `a{2,}?`,
+			shouldError: true,
+		},
+		{
+			desc:        "Character set matching every character",
+			regex:       `[^\x00-\x{10FFFF}]`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			query, err := Decompile(tc.regex)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Decompiling failed. Err=%v; Regex=%s\n", err, tc.regex)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Decompiling should have thrown an error but didn't. Regex=%s; Query=%s\n", tc.regex, query)
+				return
+			}
+
+			if tc.expectedQuery != query {
+				t.Errorf("Decompiled query does not equal expected query. Expected=%s; Decompiled=%s\n", tc.expectedQuery, query)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedQuery string
+		shouldError   bool
+	}{
+		{
+			desc:          "Simplest",
+			query:         "select   'friend'",
+			expectedQuery: "select 'friend'\n",
+		},
+		{
+			desc:          "Options",
+			query:         "set_options({find_all_matches: true, case_sensitive: false})\nselect any_chars_of( 'is','omar' )",
+			expectedQuery: "set_options({\n\tfind_all_matches: true,\n\tcase_sensitive: false,\n})\n\nselect any_chars_of('is', 'omar')\n",
+		},
+		{
+			desc:          "Short multi-line query is put on one line",
+			query:         "select\n    starts_with('Hello') +\n    any_chars() +\n    'Omar'\n",
+			expectedQuery: "select starts_with('Hello') + any_chars() + 'Omar'\n",
+		},
+		{
+			desc:          "Escapes and raw strings are kept",
+			query:         "select  'it\\'s\\t' +  `C:\\dir`",
+			expectedQuery: "select 'it\\'s\\t' + `C:\\dir`\n",
+		},
+		{
+			desc:          "Alternation is written as 'or'",
+			query:         "select 'a'|'b' +  'c' or 'd'",
+			expectedQuery: "select 'a' or 'b' + 'c' or 'd'\n",
+		},
+		{
+			desc:  "Long query is split",
+			query: "select one_plus_of(any_chars_of(from_to('A', 'Z'), from_to(0, 9), '._%+-')) + '@' + one_plus_of(any_chars_of(from_to('A', 'Z'), from_to(0, 9), '.-', 'some more chars to make the line long'))",
+			expectedQuery: `select
+	one_plus_of(any_chars_of(from_to('A', 'Z'), from_to(0, 9), '._%+-')) +
+	'@' +
+	one_plus_of(
+		any_chars_of(
+			from_to('A', 'Z'),
+			from_to(0, 9),
+			'.-',
+			'some more chars to make the line long'
+		)
+	)
+`,
+		},
+		{
+			desc: "Comments",
+			query: `// Emails
+//   e.g. 'some-email@wow.com'
+set_options({
+    case_sensitive: false, // Case doesn't matter in emails
+})
+select
+    // Name
+    one_plus_of(any_chars_of(from_to('A', 'Z'), '._%+-')) +
+    '@' + // At
+    one_plus_of(
+        any_chars_of(from_to('A', 'Z'), '.-') // Domain
+    ) // End
+// Last line`,
+			expectedQuery: `// Emails
+//   e.g. 'some-email@wow.com'
+set_options({
+	case_sensitive: false, // Case doesn't matter in emails
+})
+
+select
+	// Name
+	one_plus_of(any_chars_of(from_to('A', 'Z'), '._%+-')) +
+	'@' + // At
+	one_plus_of(
+		any_chars_of(from_to('A', 'Z'), '.-') // Domain
+	) // End
+// Last line
+`,
+		},
+
+		//
+		// Negative test cases
+		//
+		{
+			desc:        "Empty",
+			query:       "",
+			shouldError: true,
+		},
+		{
+			desc:        "Invalid query",
+			query:       "select one_plus_of('a'",
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			query, err := Format(tc.query)
+			if err != nil {
+
+				if tc.shouldError {
+					return
+				}
+
+				t.Errorf("Formatting failed. Err=%v; Query=%s\n", err, tc.query)
+				return
+			}
+
+			if tc.shouldError {
+				t.Errorf("Formatting should have thrown an error but didn't. Query=%s; Formatted=%s\n", tc.query, query)
+				return
+			}
+
+			if tc.expectedQuery != query {
+				t.Errorf("Formatted query does not equal expected query. Expected=%s; Formatted=%s\n", tc.expectedQuery, query)
+				return
+			}
+
+			// Formatting must be idempotent and must not change the produced regex
+			reformattedQuery, err := Format(query)
+			if err != nil || reformattedQuery != query {
+				t.Errorf("Formatting a formatted query changed it. Err=%v; Formatted=%s; Reformatted=%s\n", err, query, reformattedQuery)
+			}
+
+			regexString, err := NewRegexl(tc.query).CompileString(Dialect_Go)
+			if err != nil {
+				t.Fatalf("Compiling failed. Err=%v; Query=%s\n", err, tc.query)
+			}
+
+			formattedRegexString, err := NewRegexl(query).CompileString(Dialect_Go)
+			if err != nil || formattedRegexString != regexString {
+				t.Errorf("Formatted query doesn't compile to the same regex. Err=%v; Expected=%s; Got=%s\n", err, regexString, formattedRegexString)
+			}
+		})
+	}
+}
+
+func TestBuiltinDocs(t *testing.T) {
+
+	// Calling a function with no arguments fails for most functions, but must never fail because the function is unknown
+	for _, builtin := range BuiltinFuncs {
+
+		_, err := NewRegexl("select " + builtin.Name + "()").CompileString(Dialect_PCRE2)
+		if err != nil && strings.Contains(err.Error(), "unknown function") {
+			t.Errorf("Documented function '%s' is unknown to the backend. Err=%v\n", builtin.Name, err)
+		}
+
+		if _, ok := builtinSignatures[builtin.Name]; !ok && builtin.Name != "set_options" {
+			t.Errorf("Documented function '%s' has no signature in the checker\n", builtin.Name)
+		}
+	}
+
+	for _, builtin := range BuiltinOptions {
+
+		_, err := NewRegexl("set_options({" + builtin.Name + ": true}) select 'a'").CompileString(Dialect_Go)
+		if err != nil {
+			t.Errorf("Documented option '%s' can't be set. Err=%v\n", builtin.Name, err)
+		}
+
+		if _, ok := optionTypes[builtin.Name]; !ok {
+			t.Errorf("Documented option '%s' has no type in the checker\n", builtin.Name)
+		}
+	}
+
+	if _, ok := LookupBuiltin(BuiltinFuncs, "count_between"); !ok {
+		t.Errorf("Looking up count_between failed\n")
+	}
+
+	if _, ok := LookupBuiltin(BuiltinFuncs, "not_a_function"); ok {
+		t.Errorf("Looking up an unknown function succeeded\n")
+	}
+}
+
+func TestRenderError(t *testing.T) {
+
+	testCases := []struct {
+		desc           string
+		query          string
+		expectedRender string
+		// expectedLoc is the 'line:column' the message of the error should have
+		expectedLoc string
+	}{
+		{
+			desc:           "Parser error",
+			query:          "set_options({x 1}) select 'a'",
+			expectedRender: "1:14: invalid regexl query: 'x' is not a keyword, function call or literal (e.g. 'abc', 10 or true)\nset_options({x 1}) select 'a'\n             ^",
+			expectedLoc:    "1:14",
+		},
+		{
+			desc:           "Ast error on a later line",
+			query:          "select\n\tone_plus_of('a' 'b')",
+			expectedRender: "2:18: expected ',' or ')' after argument of function 'one_plus_of' but found the string 'b'\n\tone_plus_of('a' 'b')\n\t                ^~~",
+			expectedLoc:    "2:18",
+		},
+		{
+			desc:           "Backend error with multi-byte characters before it",
+			query:          "select 'é' + followed_by('x')",
+			expectedRender: "1:14: function 'followed_by' can't be used with the 'go' regex dialect because it doesn't support lookarounds\nselect 'é' + followed_by('x')\n             ^~~~~~~~~~~~~~~~",
+			expectedLoc:    "1:14",
+		},
+		{
+			desc:           "Span over multiple lines is underlined until the line ends",
+			query:          "select followed_by(\n'x')",
+			expectedRender: "1:8: function 'followed_by' can't be used with the 'go' regex dialect because it doesn't support lookarounds\nselect followed_by(\n       ^~~~~~~~~~~~",
+			expectedLoc:    "1:8",
+		},
+		{
+			desc:           "Missing select is put on the first token",
+			query:          "\nset_options({case_sensitive: true})",
+			expectedRender: "2:1: invalid regexl query: 'select' keyword is required but wasn't found\nset_options({case_sensitive: true})\n^~~~~~~~~~~",
+			expectedLoc:    "2:1",
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			err := NewRegexl(tc.query).Compile()
+			if err == nil {
+				t.Fatalf("Compiling should have thrown an error but didn't. Query=%s\n", tc.query)
+			}
+
+			rendered := RenderError(tc.query, err)
+			if rendered != tc.expectedRender {
+				t.Errorf("Rendered error does not equal expected render. Expected=\n%s\nRendered=\n%s\n", tc.expectedRender, rendered)
+			}
+
+			if strings.Contains(err.Error(), tc.query) {
+				t.Errorf("Error should not contain the query. Err=%s\n", err)
+			}
+
+			if !strings.Contains(err.Error(), "loc="+tc.expectedLoc+";") {
+				t.Errorf("Error should have the line and column of its cause. Expected=loc=%s; Err=%s\n", tc.expectedLoc, err)
+			}
+		})
+	}
+
+	loc := Locate("select\n\t'é' + 'a'", 14)
+	if loc.Line != 2 || loc.Column != 7 || loc.String() != "2:7" {
+		t.Errorf("Locate returned the wrong location. Expected=2:7; Got=%s\n", loc)
+	}
+
+	// Errors that were never given their query show the byte position
+	backendErr := &BackendError{Err: errors.New("bad"), Pos: 7}
+	if backendErr.Error() != "backend error: loc=7; err=bad" {
+		t.Errorf("Error without its query should show the byte position. Err=%s\n", backendErr)
+	}
+
+	plainErr := errors.New("plain error")
+	if RenderError("select 'a'", plainErr) != plainErr.Error() {
+		t.Errorf("Rendering an error without a span should return the error as is\n")
+	}
+}
+
+func TestErrorList(t *testing.T) {
+
+	testCases := []struct {
+		desc  string
+		query string
+		// expectedErrs has one part of the message of each expected error, in the order the errors are in the query
+		expectedErrs []string
+	}{
+		{
+			desc:         "Unknown function, bad arity and unclosed bracket",
+			query:        "select foo('a') + starts_with('a', 'b') + one_plus_of('c'",
+			expectedErrs: []string{"unknown function 'foo'", "'starts_with' must have one argument", "opening bracket without a closing bracket"},
+		},
+		{
+			desc:         "Errors on multiple lines",
+			query:        "set_options({\n\tcase_sensitive: 1,\n\tfoo: true,\n})\nselect\n\tbar() +\n\tstarts_with()",
+			expectedErrs: []string{"case_sensitive", "unknown parameter 'foo'", "unknown function 'bar'", "'starts_with' must have one argument"},
+		},
+		{
+			desc:         "Error inside arguments doesn't cause an arity error",
+			query:        "select count_between('a', 2 3) + bar()",
+			expectedErrs: []string{"expected ',' or ')' after argument of function 'count_between'", "unknown function 'bar'"},
+		},
+		{
+			desc:         "Errors inside arguments of incomplete function are still found",
+			query:        "select one_plus_of(foo(), 'a' 'b')",
+			expectedErrs: []string{"unknown function 'foo'", "expected ',' or ')'"},
+		},
+		{
+			desc:         "Problem found by tokenizer and AST is reported once",
+			query:        "select 'a' + foo",
+			expectedErrs: []string{"'foo' is not a keyword"},
+		},
+		{
+			desc:         "Plus at the end",
+			query:        "select 'a' +",
+			expectedErrs: []string{"expected an expression after '+'"},
+		},
+		{
+			desc:         "Unclosed string and unexpected slash",
+			query:        "select 'a' / starts_with('b', 'c') + 'd",
+			expectedErrs: []string{"found '/'", "'starts_with' must have one argument", "string doesn't have a closing quote"},
+		},
+		{
+			desc:         "Unclosed object",
+			query:        "set_options({case_sensitive:",
+			expectedErrs: []string{"'select' keyword is required", "opening bracket without a closing bracket", "opening bracket without a closing bracket", "expected a value after ':'"},
+		},
+		{
+			desc:         "Empty select",
+			query:        "select",
+			expectedErrs: []string{"select must be followed by at least one expression"},
+		},
+		{
+			desc:         "Different errors at the same position",
+			query:        "select from_to('a', 'b', 'c')",
+			expectedErrs: []string{"'from_to' must have two arguments", "select expressions must be patterns"},
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			err := NewRegexl(tc.query).Compile()
+			if err == nil {
+				t.Fatalf("Compiling should have thrown an error but didn't. Query=%s\n", tc.query)
+			}
+
+			var errList ErrorList
+			if !errors.As(err, &errList) {
+				t.Fatalf("Compile error should be an ErrorList. Err=%v\n", err)
+			}
+
+			errs := Errors(err)
+			if len(errs) != len(tc.expectedErrs) {
+				t.Fatalf("Expected %d errors but got %d. Errs=\n%s\n", len(tc.expectedErrs), len(errs), RenderError(tc.query, err))
+			}
+
+			for i, expectedErr := range tc.expectedErrs {
+
+				if !strings.Contains(errs[i].Error(), expectedErr) {
+					t.Errorf("Error %d should contain '%s'. Err=%s\n", i, expectedErr, errs[i])
+				}
+			}
+		})
+	}
+
+	errs := ErrorList{}
+	if errs.Err() != nil {
+		t.Errorf("Err of an empty ErrorList should be nil\n")
+	}
+
+	errs.Add(nil)
+	errs.Add(&AstError{Pos: 5, Err: errors.New("b")})
+	errs.Add(ErrorList{&ParserError{Pos: 1, Err: errors.New("a")}, &BackendError{Pos: 5, Err: errors.New("c")}})
+	errs.Add(&AstError{Pos: 5, Err: errors.New("d")})
+	errs.Add(&AstError{Pos: 5, Err: errors.New("b")})
+	errs.RemoveMultiples()
+	if len(errs) != 3 || errs[0].(*ParserError).Pos != 1 || errs[1].(*AstError).Err.Error() != "b" || errs[2].(*AstError).Err.Error() != "d" {
+		t.Errorf("RemoveMultiples should sort the errors and keep the errors of the first step at each position once. Errs=%v\n", []error(errs))
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	testCases := []struct {
+		desc  string
+		query string
+		// expectedErrs are the parts of the query each error is about, in order. Empty means the query is valid
+		expectedErrs []string
+	}{
+		{
+			desc:         "Valid query",
+			query:        "set_options({case_sensitive: true}) select starts_with(any_chars_of(from_to('a', 'z'), '_', 0)) + count_between('x', 1, 3)",
+			expectedErrs: []string{},
+		},
+		{
+			desc:         "Char set used as a pattern",
+			query:        "select one_plus_of(any_chars_of('abc'))",
+			expectedErrs: []string{},
+		},
+		{
+			desc:         "Range outside of any_chars_of",
+			query:        "select from_to('A', 'Z')",
+			expectedErrs: []string{"from_to('A', 'Z')"},
+		},
+		{
+			desc:         "Pattern passed as chars",
+			query:        "select any_chars_of(one_plus_of('a'))",
+			expectedErrs: []string{"one_plus_of('a')"},
+		},
+		{
+			desc:         "Strings passed as counts",
+			query:        "select count_between('x', 'a', 'b')",
+			expectedErrs: []string{"'a'", "'b'"},
+		},
+		{
+			desc:         "Integer added to a pattern",
+			query:        "select 'a' + 5",
+			expectedErrs: []string{"5"},
+		},
+		{
+			desc:         "Range bound with more than one character",
+			query:        "select any_chars_of(from_to('ab', 'z'))",
+			expectedErrs: []string{"'ab'"},
+		},
+		{
+			desc:         "Pattern as capture name",
+			query:        "select capture_as(one_plus_of('a'), 'b')",
+			expectedErrs: []string{"one_plus_of('a')"},
+		},
+		{
+			desc:         "Wrong scoped options",
+			query:        "select with_options({find_all_matches: true, ungreedy: 'yes'}, 'a') + with_options('a', 'b')",
+			expectedErrs: []string{"find_all_matches: true", "ungreedy: 'yes'", "'a'"},
+		},
+		{
+			desc:         "Wrong option value",
+			query:        "set_options({case_sensitive: 1, find_all_matches: 'yes'}) select 'a'",
+			expectedErrs: []string{"case_sensitive: 1", "find_all_matches: 'yes'"},
+		},
+		{
+			desc:         "Nested set_options",
+			query:        "select one_plus_of(set_options({case_sensitive: true}))",
+			expectedErrs: []string{"set_options({case_sensitive: true})"},
+		},
+		{
+			desc:         "Unknown function and wrong argument count",
+			query:        "select any_chars_of() + foo('a')",
+			expectedErrs: []string{"any_chars_of()", "foo('a')"},
+		},
+		{
+			desc:         "Arguments of unknown function are still checked",
+			query:        "select foo(from_to('a', 'bc'))",
+			expectedErrs: []string{"foo(from_to('a', 'bc'))", "'bc'"},
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			ast, err := NewRegexl(tc.query).Parse()
+			if err != nil {
+				t.Fatalf("Parsing failed. Err=%v; Query=%s\n", err, tc.query)
+			}
+
+			errs := Errors(NewChecker(ast).Check())
+			if len(errs) != len(tc.expectedErrs) {
+				t.Fatalf("Expected %d errors but got %d. Errs=%v; Query=%s\n", len(tc.expectedErrs), len(errs), errs, tc.query)
+			}
+
+			for i, err := range errs {
+
+				var checkErr *CheckError
+				if !errors.As(err, &checkErr) {
+					t.Fatalf("Expected a CheckError but got %T. Err=%v\n", err, err)
+				}
+
+				start, end := checkErr.Span()
+				if span := tc.query[start:end]; span != tc.expectedErrs[i] {
+					t.Errorf("Error %d has the wrong span. Expected=%s; Got=%s; Err=%v\n", i, tc.expectedErrs[i], span, err)
+				}
+			}
+		})
+	}
+
+	ast, err := NewRegexl("select any_chars_of(from_to('a', 'z'))").Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed. Err=%v\n", err)
+	}
+
+	checker := NewChecker(ast)
+	if err := checker.Check(); err != nil {
+		t.Fatalf("Checking failed. Err=%v\n", err)
+	}
+
+	anyCharsOf := ast.Nodes[0].(*SelectStmt).Es[0].(*FuncExpr)
+	if typ := checker.Types[anyCharsOf]; typ != ExprType_CharSet {
+		t.Errorf("any_chars_of has the wrong type. Expected=%s; Got=%s\n", ExprType_CharSet, typ)
+	}
+
+	if typ := checker.Types[anyCharsOf.Args[0]]; typ != ExprType_CharRange {
+		t.Errorf("from_to has the wrong type. Expected=%s; Got=%s\n", ExprType_CharRange, typ)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+
+	testCases := []struct {
+		desc             string
+		query            string
+		expectedFuncName string
+		// expectedSpan is the part of the query the error is about
+		expectedSpan string
+	}{
+		{
+			desc:             "Unknown function",
+			query:            "select 'a' + foo('b')",
+			expectedFuncName: "foo",
+			expectedSpan:     "foo('b')",
+		},
+		{
+			desc:             "Wrong number of arguments of the second of two calls",
+			query:            "select starts_with('a') + starts_with('b', 'c')",
+			expectedFuncName: "starts_with",
+			expectedSpan:     "starts_with('b', 'c')",
+		},
+		{
+			desc:             "Nested call",
+			query:            "select one_plus_of(any_chars_of(from_to('a')))",
+			expectedFuncName: "from_to",
+			expectedSpan:     "from_to('a')",
+		},
+		{
+			desc:             "Wrong argument",
+			query:            "select capture_as('1st', 'a')",
+			expectedFuncName: "capture_as",
+			expectedSpan:     "'1st'",
+		},
+		{
+			desc:             "Unknown option",
+			query:            "set_options({ foo: true }) select 'a'",
+			expectedFuncName: "set_options",
+			expectedSpan:     "foo: true",
+		},
+		{
+			desc:             "Option value that isn't a literal",
+			query:            "set_options({case_sensitive: any_char()}) select 'a'",
+			expectedFuncName: "set_options",
+			expectedSpan:     "case_sensitive: any_char()",
+		},
+		{
+			desc:             "Scoped option value that isn't a literal",
+			query:            "select with_options({multiline: 'a' + 'b'}, 'c')",
+			expectedFuncName: "with_options",
+			expectedSpan:     "multiline: 'a' + 'b'",
+		},
+		{
+			desc:             "Pattern passed to any_chars_of",
+			query:            "select any_chars_of('a', one_plus_of('b'))",
+			expectedFuncName: "any_chars_of",
+			expectedSpan:     "one_plus_of('b')",
+		},
+		{
+			desc:             "Alternation passed to none_of_chars",
+			query:            "select 'x' + none_of_chars('a' or 'b')",
+			expectedFuncName: "none_of_chars",
+			expectedSpan:     "'a' or 'b'",
+		},
+		{
+			desc:             "Empty character set",
+			query:            "select optional(any_chars_of())",
+			expectedFuncName: "any_chars_of",
+			expectedSpan:     "any_chars_of()",
+		},
+		{
+			desc:             "Empty negated character set",
+			query:            "select none_of_chars()",
+			expectedFuncName: "none_of_chars",
+			expectedSpan:     "none_of_chars()",
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			// The checker finds these errors first when compiling, so the backend is run on its own
+			ast, err := NewRegexl(tc.query).Parse()
+			if err != nil {
+				t.Fatalf("Parsing failed. Err=%v; Query=%s\n", err, tc.query)
+			}
+
+			backend, _ := NewBackend(Dialect_Go)
+			_, _, err = backend.AstToRegexString(ast)
+
+			var backendErr *BackendError
+			if !errors.As(err, &backendErr) {
+				t.Fatalf("Backend should have returned a BackendError. Err=%v; Query=%s\n", err, tc.query)
+			}
+
+			if backendErr.FuncName != tc.expectedFuncName {
+				t.Errorf("BackendError has the wrong function name. Expected=%s; Got=%s\n", tc.expectedFuncName, backendErr.FuncName)
+			}
+
+			start, end := backendErr.Span()
+			if span := tc.query[start:end]; span != tc.expectedSpan {
+				t.Errorf("BackendError has the wrong span. Expected=%s; Got=%s\n", tc.expectedSpan, span)
+			}
+		})
+	}
+
+	// Each problem is reported once, with its span
+	_, err := NewRegexl("select count_between('a', 1, 1001)").CompileString(Dialect_JavaScript)
+	if errs := Errors(err); len(errs) != 1 || errorStart(errs[0]) != 29 {
+		t.Errorf("JavaScript should report the problem once with its span. Errs=%v\n", errs)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		query         string
+		expectedValue string
+		// expectedErrSpan is the part of the query the error is about, and is empty if there should be no error
+		expectedErrSpan string
+	}{
+		{
+			desc:          "Simple escapes",
+			query:         `select 'a\tb\nc\rd'`,
+			expectedValue: "a\tb\nc\rd",
+		},
+		{
+			desc:          "Quote and backslash",
+			query:         `select 'it\'s C:\\'`,
+			expectedValue: `it's C:\`,
+		},
+		{
+			desc:          "Unicode code points",
+			query:         `select '\u{1F600}\u{e9}'`,
+			expectedValue: "\U0001F600\u00e9",
+		},
+		{
+			desc:          "Raw string",
+			query:         "select `C:\\dir\\'n'`",
+			expectedValue: `C:\dir\'n'`,
+		},
+		{
+			desc:            "Unknown escape",
+			query:           `select 'a\qb'`,
+			expectedValue:   "ab",
+			expectedErrSpan: `\q`,
+		},
+		{
+			desc:            "Unicode escape without brackets",
+			query:           `select 'a\u00e9'`,
+			expectedValue:   "a00e9",
+			expectedErrSpan: `\u`,
+		},
+		{
+			desc:            "Invalid code point",
+			query:           `select 'a\u{110000}'`,
+			expectedValue:   "a",
+			expectedErrSpan: `\u{110000}`,
+		},
+		{
+			desc:            "Unclosed raw string",
+			query:           "select `abc",
+			expectedValue:   "abc",
+			expectedErrSpan: "`abc",
+		},
+	}
+
+	for _, tc := range testCases {
+
+		t.Run(tc.desc, func(t *testing.T) {
+
+			tokens, err := NewParser(tc.query).Tokenize()
+
+			stringIndex := slices.IndexFunc(tokens, func(t Token) bool { return t.Type == TokenType_String })
+			if stringIndex == -1 {
+				t.Fatalf("No string token was found. Tokens=%+v\n", tokens)
+			}
+
+			stringToken := tokens[stringIndex]
+			if stringToken.Val != tc.expectedValue {
+				t.Errorf("String has the wrong value. Expected=%q; Got=%q\n", tc.expectedValue, stringToken.Val)
+			}
+
+			// The string always goes to the end of these queries
+			if stringToken.EndPos() != TokenPos(len(tc.query)) {
+				t.Errorf("String has the wrong end. Expected=%d; Got=%d\n", len(tc.query), stringToken.EndPos())
+			}
+
+			if tc.expectedErrSpan == "" {
+
+				if err != nil {
+					t.Errorf("Tokenizing failed. Err=%v\n", err)
+				}
+
+				return
+			}
+
+			errs := Errors(err)
+			if len(errs) != 1 {
+				t.Fatalf("Expected one error but got %d. Err=%v\n", len(errs), err)
+			}
+
+			span, _ := ErrorSpan(tc.query, errs[0])
+			if got := tc.query[span.Start.Pos:span.End.Pos]; got != tc.expectedErrSpan {
+				t.Errorf("Error has the wrong span. Expected=%s; Got=%s; Err=%v\n", tc.expectedErrSpan, got, err)
+			}
+		})
+	}
+
+	// Escaped strings must compile to what they stand for
+	re := NewRegexl("select 'a\\tb'").MustCompile().CompiledRegexp
+	if !re.MatchString("a\tb") {
+		t.Errorf("Regex of an escaped tab doesn't match a tab. Regex=%s\n", re.String())
+	}
+}
+
+func TestTokenLocations(t *testing.T) {
+
+	// Multi-byte characters, a raw string over two lines and comments, which are the cases where the running line and column can go wrong
+	query := "// héllo 😀\nselect 'é😀' +\n\t`a\nb` + // c\n  any_chars_of('x')"
+
+	tokens, err := NewParser(query).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenizing failed. Err=%v\n", err)
+	}
+
+	for _, tok := range tokens {
+
+		loc := Locate(query, tok.Pos)
+		if tok.Line != loc.Line || tok.Column != loc.Column {
+			t.Errorf("Token has the wrong location. Token=%q; Expected=%d:%d; Got=%d:%d\n", tok.Val, loc.Line, loc.Column, tok.Line, tok.Column)
+		}
+	}
+
+	lastToken := tokens[len(tokens)-1]
+	if lastToken.Val != ")" || lastToken.Line != 5 || lastToken.Column != 19 {
+		t.Errorf("Last token has the wrong location. Expected=):5:19; Got=%s:%d:%d\n", lastToken.Val, lastToken.Line, lastToken.Column)
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	ast, err := NewRegexl(`select starts_with('hello') + any_chars()`).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed. Err=%v\n", err)
+	}
+
+	sb := strings.Builder{}
+	ast.FprintTree(&sb)
+
+	expectedTree := "|-- select\n|   |-- TokenType_Plus\n|   |   |-- starts_with\n|   |   |   |-- hello\n|   |   |-- any_chars\n"
+	if sb.String() != expectedTree {
+		t.Fatalf("Printed AST tree does not equal expected tree. Expected=\n%s\nPrinted=\n%s\n", expectedTree, sb.String())
+	}
+
+	if _, err := NewRegexl(``).Parse(); err == nil {
+		t.Fatalf("Parsing an empty query should have thrown an error but didn't\n")
+	}
+}
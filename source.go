@@ -0,0 +1,163 @@
+package regexl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceLocation is a position in a query as a line and column, which is what humans use to find things in a query
+type SourceLocation struct {
+	Pos TokenPos
+	// Line is 1-based
+	Line int
+	// Column is 1-based and counts characters, not bytes
+	Column int
+}
+
+// String returns the location as 'line:column' (e.g. '3:14')
+func (sl SourceLocation) String() string {
+	return fmt.Sprintf("%d:%d", sl.Line, sl.Column)
+}
+
+// SourceSpan is a part of a query, where End is the location right after the last character of the span
+type SourceSpan struct {
+	Start SourceLocation
+	End   SourceLocation
+}
+
+// Locate returns the line and column of the position in the query. Positions outside the query are moved to its start or end
+func Locate(query string, pos TokenPos) SourceLocation {
+
+	pos = max(0, min(pos, TokenPos(len(query))))
+
+	lineStart := strings.LastIndexByte(query[:pos], '\n') + 1
+	return SourceLocation{
+		Pos:    pos,
+		Line:   strings.Count(query[:pos], "\n") + 1,
+		Column: utf8.RuneCountInString(query[lineStart:pos]) + 1,
+	}
+}
+
+// queryHolder is implemented by the SpanErrors of this package, which only know the byte positions of what caused them until they are given the query
+type queryHolder interface {
+	setQuery(query string)
+}
+
+// setErrorsQuery gives the query to all the errors in err, so that their messages show lines and columns (e.g. 'loc=3:14') instead of byte positions
+func setErrorsQuery(err error, query string) {
+
+	for _, e := range Errors(err) {
+
+		var qh queryHolder
+		if errors.As(e, &qh) {
+			qh.setQuery(query)
+		}
+	}
+}
+
+// errorLoc returns the location of pos used in error messages, which is 'line:column' if the query is known and the byte position otherwise
+func errorLoc(query string, pos TokenPos) string {
+
+	if query == "" {
+		return strconv.Itoa(int(pos))
+	}
+
+	return Locate(query, pos).String()
+}
+
+// SpanError is implemented by errors that know which part of the query caused them, which are ParserError, AstError, CheckError and BackendError
+type SpanError interface {
+	error
+	// Span returns the positions of the first byte of what caused the error and of the byte after it
+	Span() (start, end TokenPos)
+	// Unwrap returns the error without the position
+	Unwrap() error
+}
+
+var (
+	_ SpanError = &ParserError{}
+	_ SpanError = &AstError{}
+	_ SpanError = &CheckError{}
+	_ SpanError = &BackendError{}
+)
+
+// errorSpan returns the span of an error from its start and end, where an end that isn't after the start means the error is about one character
+func errorSpan(pos, end TokenPos) (TokenPos, TokenPos) {
+
+	if end <= pos {
+		return pos, pos + 1
+	}
+
+	return pos, end
+}
+
+// ErrorSpan returns the part of the query that caused the error, and false if the error isn't a SpanError
+func ErrorSpan(query string, err error) (SourceSpan, bool) {
+
+	var spanErr SpanError
+	if !errors.As(err, &spanErr) {
+		return SourceSpan{}, false
+	}
+
+	start, end := spanErr.Span()
+	return SourceSpan{
+		Start: Locate(query, start),
+		End:   Locate(query, end),
+	}, true
+}
+
+// RenderError returns the error as 'line:column: message' followed by the line of the query that caused the error, where the cause is underlined like this:
+//
+//	2:2: function 'starts_with' must have one argument but was passed 2 arguments
+//		starts_with('a', 'b') +
+//		^~~~~~~~~~~~~~~~~~~~~
+//
+// The underline stops at the end of the line if the cause spans multiple lines. Each error of an ErrorList is rendered on its own,
+// and errors that aren't a SpanError are returned as is
+func RenderError(query string, err error) string {
+
+	if el, ok := err.(ErrorList); ok {
+		return renderErrorList(query, el)
+	}
+
+	var spanErr SpanError
+	if !errors.As(err, &spanErr) {
+		return err.Error()
+	}
+
+	span, _ := ErrorSpan(query, err)
+
+	lineStart := strings.LastIndexByte(query[:span.Start.Pos], '\n') + 1
+	lineEnd := strings.IndexByte(query[lineStart:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(query)
+	} else {
+		lineEnd += lineStart
+	}
+
+	line := strings.TrimSuffix(query[lineStart:lineEnd], "\r")
+
+	// Keep tabs so the underline lines up with the line however wide tabs are shown
+	sb := strings.Builder{}
+	for _, r := range query[lineStart:span.Start.Pos] {
+
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	underlineLen := 0
+	if span.End.Line == span.Start.Line {
+		underlineLen = max(1, span.End.Column-span.Start.Column)
+	} else {
+		underlineLen = max(1, utf8.RuneCountInString(strings.TrimSuffix(query[span.Start.Pos:lineEnd], "\r")))
+	}
+
+	sb.WriteString("^" + strings.Repeat("~", underlineLen-1))
+	return fmt.Sprintf("%s: %s\n%s\n%s", span.Start, spanErr.Unwrap(), line, sb.String())
+}
@@ -31,6 +31,8 @@ const (
 	TokenType_Object_Param
 	TokenType_Function_Name
 	TokenType_Keyword
+	// TokenType_Or is the alternation operator, written as 'or' or '|'
+	TokenType_Or
 )
 
 type TokenPos int
@@ -39,6 +41,23 @@ type Token struct {
 	Val  string
 	Type TokenType
 	Pos  TokenPos
+	// Line is the 1-based line of the query the token starts on
+	Line int
+	// Column is the 1-based column of the query the token starts on, and counts characters, not bytes
+	Column int
+	// Raw is the string as written in the query including its quotes and escapes (e.g. 'a\tb'), while Val has the string it stands for.
+	// Raw is only set for strings
+	Raw string `json:",omitempty"`
+}
+
+// operator returns how the operator of a binary expression of this type is written in the canonical style (e.g. '+' or 'or')
+func (tt TokenType) operator() string {
+
+	if tt == TokenType_Or {
+		return "or"
+	}
+
+	return "+"
 }
 
 func (t *Token) MakeEmpty() {
@@ -50,12 +69,33 @@ func (t *Token) MakeEmpty() {
 	t.Val = ""
 	t.Type = TokenType_Unknown
 	t.Pos = -1
+	t.Line = 0
+	t.Column = 0
+	t.Raw = ""
 }
 
 func (t *Token) IsEmpty() bool {
 	return t == nil || (t.Type == TokenType_Unknown && t.Val == "" && t.Pos == -1)
 }
 
+// EndPos returns the position after the last byte of the token in the query
+func (t *Token) EndPos() TokenPos {
+
+	switch {
+
+	// Escapes make the value of a string differ from what is written in the query
+	case t.Type == TokenType_String && t.Raw != "":
+		return t.Pos + TokenPos(len(t.Raw))
+
+	// Quotes and the comment start aren't part of the value
+	case t.Type == TokenType_String, t.Type == TokenType_Comment:
+		return t.Pos + TokenPos(len(t.Val)) + 2
+
+	default:
+		return t.Pos + TokenPos(len(t.Val))
+	}
+}
+
 func (t *Token) HasLoc() bool {
 	return t.Pos != -1
 }
@@ -26,11 +26,12 @@ func _() {
 	_ = x[TokenType_Object_Param-15]
 	_ = x[TokenType_Function_Name-16]
 	_ = x[TokenType_Keyword-17]
+	_ = x[TokenType_Or-18]
 }
 
-const _TokenType_name = "TokenType_UnknownTokenType_SpaceTokenType_StringTokenType_IntTokenType_FloatTokenType_OperatorTokenType_OpenBracketTokenType_CloseBracketTokenType_OpenCurlyBracketTokenType_CloseCurlyBracketTokenType_ColonTokenType_CommaTokenType_BoolTokenType_PlusTokenType_CommentTokenType_Object_ParamTokenType_Function_NameTokenType_Keyword"
+const _TokenType_name = "TokenType_UnknownTokenType_SpaceTokenType_StringTokenType_IntTokenType_FloatTokenType_OperatorTokenType_OpenBracketTokenType_CloseBracketTokenType_OpenCurlyBracketTokenType_CloseCurlyBracketTokenType_ColonTokenType_CommaTokenType_BoolTokenType_PlusTokenType_CommentTokenType_Object_ParamTokenType_Function_NameTokenType_KeywordTokenType_Or"
 
-var _TokenType_index = [...]uint16{0, 17, 32, 48, 61, 76, 94, 115, 137, 163, 190, 205, 220, 234, 248, 265, 287, 310, 327}
+var _TokenType_index = [...]uint16{0, 17, 32, 48, 61, 76, 94, 115, 137, 163, 190, 205, 220, 234, 248, 265, 287, 310, 327, 339}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {